@@ -5,6 +5,7 @@ go 1.24.4
 require (
 	go.mongodb.org/mongo-driver v1.17.4
 	golang.org/x/net v0.41.0
+	golang.org/x/sync v0.15.0
 )
 
 require (
@@ -33,7 +34,6 @@ require (
 	github.com/youmark/pkcs8 v0.0.0-20240726163527-a2c0da244d78 // indirect
 	golang.org/x/crypto v0.39.0 // indirect
 	golang.org/x/oauth2 v0.27.0 // indirect
-	golang.org/x/sync v0.15.0 // indirect
 	golang.org/x/sys v0.33.0 // indirect
 	golang.org/x/text v0.26.0 // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
@@ -2,12 +2,16 @@ package end_to_end_tests
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"sane-discourse-backend/internal/handlers"
 	"sane-discourse-backend/internal/models"
+	"sane-discourse-backend/internal/services"
+	"sane-discourse-backend/pkg/types"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 func TestEverything(t *testing.T) {
@@ -44,7 +48,7 @@ func TestEverything(t *testing.T) {
 	}
 	assert.Equal(t, "On Caring — EA Forum", post.Title)
 	assert.Equal(t, postURL, post.URL)
-	assert.Equal(t, "article", post.Type)
+	assert.Equal(t, types.PostTypeBlog, post.Type)
 	assert.Equal(t, "", post.Author)
 	assert.NotNil(t, post.ID)
 	assert.Equal(t, http.StatusOK, createPostResponse.Result().StatusCode)
@@ -66,25 +70,262 @@ func TestEverything(t *testing.T) {
 	assert.NotNil(t, addedPost.ID)
 	assert.Equal(t, http.StatusOK, addPostResponse.Result().StatusCode)
 
+	assert.Equal(t, "https://forum.effectivealtruism.org/posts/hkimyETEo76hJ6NpW/on-caring", addedPost.CanonicalURL)
+
+	// Test Add Post returns the existing post for other links to the same page
+	for _, variant := range []string{
+		"http://forum.effectivealtruism.org/posts/hkimyETEo76hJ6NpW/on-caring/",
+		"https://www.forum.effectivealtruism.org/posts/hkimyETEo76hJ6NpW/on-caring?utm_source=twitter&fbclid=abc",
+		"https://forum.effectivealtruism.org/posts/hkimyETEo76hJ6NpW/on-caring#comments",
+	} {
+		duplicatePost := post
+		duplicatePost.URL = variant
+		duplicateResponse := PerformRequest(r, "PUT", "/user/posts/add", handlers.AddPostRequest{Post: duplicatePost})
+		duplicate := models.Post{}
+		err = json.Unmarshal(duplicateResponse.Body.Bytes(), &duplicate)
+		if err != nil {
+			t.Fatalf("Failed to unmarshal add post response: %v", err)
+		}
+		assert.Equal(t, http.StatusOK, duplicateResponse.Result().StatusCode)
+		assert.Equal(t, addedPost.ID, duplicate.ID, variant)
+	}
+
+	// Test Create Post returns the stored post for links that already are one
+	createPostResponse = PerformRequest(r, "PUT", "/user/posts/create", handlers.CreatePostRequest{
+		URL: postURL + "?utm_source=newsletter",
+	})
+	cachedPost := models.Post{}
+	err = json.Unmarshal(createPostResponse.Body.Bytes(), &cachedPost)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal create post response: %v", err)
+	}
+	assert.Equal(t, http.StatusOK, createPostResponse.Result().StatusCode)
+	assert.Equal(t, addedPost.ID, cachedPost.ID)
+	assert.Equal(t, addedPost.Title, cachedPost.Title)
+
+	// Test Add Post rejects URLs that aren't web pages
+	invalidURLPost := post
+	invalidURLPost.URL = "javascript:alert(1)"
+	addPostResponse = PerformRequest(r, "PUT", "/user/posts/add", handlers.AddPostRequest{Post: invalidURLPost})
+	assert.Equal(t, http.StatusBadRequest, addPostResponse.Result().StatusCode)
+
+	// Test Add Post rejects types outside the PostType enum
+	invalidTypePost := post
+	invalidTypePost.URL = "https://example.com/raw-og-type"
+	invalidTypePost.Type = "video.other"
+	addPostResponse = PerformRequest(r, "PUT", "/user/posts/add", handlers.AddPostRequest{Post: invalidTypePost})
+	assert.Equal(t, http.StatusBadRequest, addPostResponse.Result().StatusCode)
+
+	// Test Add Reaction replaces the existing value on the same axis
+	addReactionRequest := handlers.ReactionRequest{
+		PostID:       addedPost.ID,
+		ReactionType: types.ReactionTypeStrongAgree,
+	}
+	addReactionResponse := PerformRequest(r, "PUT", "/user/reactions/add", addReactionRequest)
+	reaction := models.Reaction{}
+	err = json.Unmarshal(addReactionResponse.Body.Bytes(), &reaction)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal add reaction response: %v", err)
+	}
+	assert.Equal(t, http.StatusOK, addReactionResponse.Result().StatusCode)
+	assert.Equal(t, types.ReactionValueStrongPositive, reaction.Agreement)
+
+	voteRequest := handlers.ReactionRequest{
+		PostID: addedPost.ID,
+		Axis:   types.ReactionAxisVote,
+		Value:  types.ReactionValuePositive,
+	}
+	addReactionResponse = PerformRequest(r, "PUT", "/user/reactions/add", voteRequest)
+	reaction = models.Reaction{}
+	err = json.Unmarshal(addReactionResponse.Body.Bytes(), &reaction)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal add reaction response: %v", err)
+	}
+	assert.Equal(t, http.StatusOK, addReactionResponse.Result().StatusCode)
+	assert.Equal(t, types.ReactionValueStrongPositive, reaction.Agreement)
+	assert.Equal(t, types.ReactionValuePositive, reaction.Vote)
+
+	// Test Get Post Reactions
+	getReactionsResponse := PerformRequest(r, "GET", "/posts/"+addedPost.ID.Hex()+"/reactions", nil)
+	var postReactions []models.Reaction
+	err = json.Unmarshal(getReactionsResponse.Body.Bytes(), &postReactions)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal post reactions response: %v", err)
+	}
+	assert.Equal(t, http.StatusOK, getReactionsResponse.Result().StatusCode)
+	assert.Equal(t, 1, len(postReactions), "Post should have one reaction per user")
+
+	// Test Delete Reaction clears only its axis
+	deleteReactionResponse := PerformRequest(r, "DELETE", "/user/reactions/delete", voteRequest)
+	remainingReaction := models.Reaction{}
+	err = json.Unmarshal(deleteReactionResponse.Body.Bytes(), &remainingReaction)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal delete reaction response: %v", err)
+	}
+	assert.Equal(t, http.StatusOK, deleteReactionResponse.Result().StatusCode)
+	assert.Equal(t, types.ReactionValueStrongPositive, remainingReaction.Agreement)
+	assert.Equal(t, types.ReactionValueNone, remainingReaction.Vote)
+
+	// Test Invalid Reactions
+	invalidTypeRequest := handlers.ReactionRequest{
+		PostID:       addedPost.ID,
+		ReactionType: "sideways",
+	}
+	invalidReactionResponse := PerformRequest(r, "PUT", "/user/reactions/add", invalidTypeRequest)
+	assert.Equal(t, http.StatusBadRequest, invalidReactionResponse.Result().StatusCode)
+	invalidValueRequest := handlers.ReactionRequest{
+		PostID: addedPost.ID,
+		Axis:   types.ReactionAxisVote,
+		Value:  3,
+	}
+	invalidReactionResponse = PerformRequest(r, "PUT", "/user/reactions/add", invalidValueRequest)
+	assert.Equal(t, http.StatusBadRequest, invalidReactionResponse.Result().StatusCode)
+
 	// Test Get User Posts
 	getUserPostsResponse := PerformRequest(r, "GET", "/user/posts", nil)
-	var userPosts []models.Post
-	err = json.Unmarshal(getUserPostsResponse.Body.Bytes(), &userPosts)
+	var userPostsPage models.PostPage
+	err = json.Unmarshal(getUserPostsResponse.Body.Bytes(), &userPostsPage)
 	if err != nil {
 		t.Fatalf("Failed to unmarshal user posts response: %v", err)
 	}
 	assert.Equal(t, http.StatusOK, getUserPostsResponse.Result().StatusCode)
+	userPosts := userPostsPage.Posts
 	assert.Equal(t, len(userPosts), 1, "User should have 1 post")
+	assert.Empty(t, userPostsPage.NextCursor)
+	assert.NotNil(t, userPosts[0].Reactions)
+	assert.GreaterOrEqual(t, userPosts[0].Reactions.Counts[types.ReactionTypeStrongAgree], 1)
+	assert.NotNil(t, userPosts[0].MyReaction)
+	assert.Equal(t, types.ReactionValueStrongPositive, userPosts[0].MyReaction.Agreement)
 
 	// Test Get Feed
 	getFeedResponse := PerformRequest(r, "GET", "/home", nil)
-	var feedPosts []models.Post
-	err = json.Unmarshal(getFeedResponse.Body.Bytes(), &feedPosts)
+	var feedPage models.PostPage
+	err = json.Unmarshal(getFeedResponse.Body.Bytes(), &feedPage)
 	if err != nil {
 		t.Fatalf("Failed to unmarshal feed response: %v", err)
 	}
 	assert.Equal(t, http.StatusOK, getFeedResponse.Result().StatusCode)
+	feedPosts := feedPage.Posts
 	assert.GreaterOrEqual(t, len(feedPosts), 1, "Feed should have at least 1 post")
+	for _, feedPost := range feedPosts {
+		assert.NotNil(t, feedPost.Reactions)
+		if feedPost.ID == addedPost.ID {
+			assert.NotNil(t, feedPost.MyReaction)
+			assert.GreaterOrEqual(t, feedPost.Reactions.Scores[types.ReactionAxisAgreement], 2)
+		}
+	}
+
+	// Test Feed Rankings
+	for _, ranking := range []string{"top", "newest", "hot", "important", "controversial"} {
+		rankedFeedResponse := PerformRequest(r, "GET", "/home?sort="+ranking, nil)
+		var rankedPage models.PostPage
+		err = json.Unmarshal(rankedFeedResponse.Body.Bytes(), &rankedPage)
+		if err != nil {
+			t.Fatalf("Failed to unmarshal %s feed response: %v", ranking, err)
+		}
+		assert.Equal(t, http.StatusOK, rankedFeedResponse.Result().StatusCode)
+		if ranking == "top" || ranking == "newest" {
+			assert.Equal(t, len(feedPosts), len(rankedPage.Posts))
+		} else {
+			// Computed rankings only rank posts that can score
+			assert.LessOrEqual(t, len(rankedPage.Posts), len(feedPosts))
+		}
+	}
+	newestFeedResponse := PerformRequest(r, "GET", "/home?sort=newest", nil)
+	var newestPage models.PostPage
+	json.Unmarshal(newestFeedResponse.Body.Bytes(), &newestPage)
+	newestPosts := newestPage.Posts
+	for i := 1; i < len(newestPosts); i++ {
+		assert.True(t, newestPosts[i-1].ID.Timestamp().Compare(newestPosts[i].ID.Timestamp()) >= 0)
+	}
+	unknownRankingResponse := PerformRequest(r, "GET", "/home?sort=random", nil)
+	assert.Equal(t, http.StatusBadRequest, unknownRankingResponse.Result().StatusCode)
+
+	// Test Feed Pagination walks the whole feed without duplicates
+	seenPosts := map[string]bool{}
+	cursor := ""
+	for {
+		pageResponse := PerformRequest(r, "GET", "/home?sort=hot&limit=1&cursor="+cursor, nil)
+		var page models.PostPage
+		err = json.Unmarshal(pageResponse.Body.Bytes(), &page)
+		if err != nil {
+			t.Fatalf("Failed to unmarshal feed page response: %v", err)
+		}
+		assert.Equal(t, http.StatusOK, pageResponse.Result().StatusCode)
+		assert.LessOrEqual(t, len(page.Posts), 1)
+		for _, pagePost := range page.Posts {
+			assert.False(t, seenPosts[pagePost.ID.Hex()], "Post should appear only once")
+			seenPosts[pagePost.ID.Hex()] = true
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+	}
+	assert.Equal(t, len(feedPosts), len(seenPosts))
+
+	// Test Feed Pagination skips and repeats no posts when reactions change
+	// the ranking between pages
+	for i := 0; i < 3; i++ {
+		PerformRequest(r, "PUT", "/user/posts/add", handlers.AddPostRequest{Post: models.Post{
+			Title:       fmt.Sprintf("Pagination %d", i),
+			Description: "A post to page through",
+			Author:      userName,
+			URL:         fmt.Sprintf("https://example.com/pagination/%s", primitive.NewObjectID().Hex()),
+		}})
+	}
+	var rankingPage models.PostPage
+	json.Unmarshal(PerformRequest(r, "GET", "/home?sort=top&limit=100", nil).Body.Bytes(), &rankingPage)
+	expectedRanking := []primitive.ObjectID{}
+	for _, rankedPost := range rankingPage.Posts {
+		expectedRanking = append(expectedRanking, rankedPost.ID)
+	}
+	pagedRanking := []primitive.ObjectID{}
+	cursor = ""
+	for len(pagedRanking) < len(expectedRanking) {
+		pageResponse := PerformRequest(r, "GET", "/home?sort=top&limit=1&cursor="+cursor, nil)
+		var page models.PostPage
+		err = json.Unmarshal(pageResponse.Body.Bytes(), &page)
+		if err != nil {
+			t.Fatalf("Failed to unmarshal feed page response: %v", err)
+		}
+		assert.Equal(t, http.StatusOK, pageResponse.Result().StatusCode)
+		for _, pagePost := range page.Posts {
+			pagedRanking = append(pagedRanking, pagePost.ID)
+		}
+		if len(pagedRanking) == 1 {
+			// Another user moves the last post above the first one
+			PerformRequest(r, "PUT", "/auth/login", handlers.LoginUserRequest{Name: "Ana", Email: "Ana@Tom.com"})
+			PerformRequest(r, "PUT", "/user/reactions/add", handlers.ReactionRequest{
+				PostID:       expectedRanking[len(expectedRanking)-1],
+				ReactionType: types.ReactionTypeStrongUpvote,
+			})
+			PerformRequest(r, "PUT", "/auth/login", loginRequest)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+	}
+	assert.Equal(t, expectedRanking[0], pagedRanking[0])
+	assert.ElementsMatch(t, expectedRanking, pagedRanking)
+	invalidCursorResponse := PerformRequest(r, "GET", "/home?cursor=not-a-cursor", nil)
+	assert.Equal(t, http.StatusBadRequest, invalidCursorResponse.Result().StatusCode)
+	invalidLimitResponse := PerformRequest(r, "GET", "/home?limit=1000", nil)
+	assert.Equal(t, http.StatusBadRequest, invalidLimitResponse.Result().StatusCode)
+
+	// Test Personalized Feed
+	personalizedFeedResponse := PerformRequest(r, "GET", "/home?scope=personalized", nil)
+	var personalizedPage models.PostPage
+	err = json.Unmarshal(personalizedFeedResponse.Body.Bytes(), &personalizedPage)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal personalized feed response: %v", err)
+	}
+	assert.Equal(t, http.StatusOK, personalizedFeedResponse.Result().StatusCode)
+	personalizedSortResponse := PerformRequest(r, "GET", "/home?scope=personalized&sort=newest", nil)
+	assert.Equal(t, http.StatusBadRequest, personalizedSortResponse.Result().StatusCode)
+	unknownScopeResponse := PerformRequest(r, "GET", "/home?scope=everything", nil)
+	assert.Equal(t, http.StatusBadRequest, unknownScopeResponse.Result().StatusCode)
 
 	// Test Add Header Component to Userpage
 	addHeaderRequest := handlers.AddComponentRequest{
@@ -108,7 +349,8 @@ func TestEverything(t *testing.T) {
 
 	// Test Add Paragraph Component to Userpage
 	addParagraphRequest := handlers.AddComponentRequest{
-		Index: 1,
+		Version: userpageAfterHeader.Version,
+		Index:   1,
 		Component: models.Component{
 			Paragraph: &models.PragraphComponent{
 				Content: "This is a paragraph describing my interests.",
@@ -126,7 +368,8 @@ func TestEverything(t *testing.T) {
 
 	// Test Add Post Component to Userpage
 	addPostComponentRequest := handlers.AddComponentRequest{
-		Index: 2,
+		Version: userpageAfterParagraph.Version,
+		Index:   2,
 		Component: models.Component{
 			Post: &models.PostComponent{
 				PostID: addedPost.ID,
@@ -142,10 +385,13 @@ func TestEverything(t *testing.T) {
 	}
 	assert.Equal(t, http.StatusOK, addPostComponentResponse.Result().StatusCode)
 	assert.NotNil(t, userpageAfterPostComponent.Components[2].Post)
+	assert.NotNil(t, userpageAfterPostComponent.Components[2].Post.Post)
+	assert.Equal(t, addedPost.URL, userpageAfterPostComponent.Components[2].Post.Post.URL)
 
 	// Test Add Divider Component to Userpage
 	addDividerRequest := handlers.AddComponentRequest{
-		Index: 3,
+		Version: userpageAfterPostComponent.Version,
+		Index:   3,
 		Component: models.Component{
 			Divider: &models.DividerComponent{
 				Style: models.RegularDevider,
@@ -163,6 +409,7 @@ func TestEverything(t *testing.T) {
 
 	// Test Move Component in Userpage
 	moveComponentRequest := handlers.MoveComponentRequest{
+		Version:   userpageAfterDivider.Version,
 		PrevIndex: 3,
 		NewIndex:  1,
 	}
@@ -176,4 +423,639 @@ func TestEverything(t *testing.T) {
 	assert.NotNil(t, userpageAfterMove.Components[1].Divider)
 	assert.NotNil(t, userpageAfterMove.Components[2].Paragraph)
 	assert.NotNil(t, userpageAfterMove.Components[3].Post)
+
+	// Test Components referencing unknown posts are rejected
+	addDanglingResponse := PerformRequest(r, "PUT", "/userpage/component/add", handlers.AddComponentRequest{
+		Version: userpageAfterMove.Version,
+		Index:   len(userpageAfterMove.Components),
+		Component: models.Component{
+			Post: &models.PostComponent{
+				PostID: primitive.NewObjectID(),
+				Size:   models.PostComponentSizeSmall,
+			},
+		},
+	})
+	var danglingErrors handlers.ValidationErrorResponse
+	err = json.Unmarshal(addDanglingResponse.Body.Bytes(), &danglingErrors)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal validation response: %v", err)
+	}
+	assert.Equal(t, http.StatusUnprocessableEntity, addDanglingResponse.Result().StatusCode)
+	assert.Equal(t, []services.FieldError{{Field: "component.post.post_id", Message: "post does not exist"}}, danglingErrors.Fields)
+
+	// Test Invalid Components report every invalid field
+	invalidBatchResponse := PerformRequest(r, "PUT", "/userpage/batch", handlers.BatchRequest{
+		Version: userpageAfterMove.Version,
+		Operations: []handlers.BatchOperationRequest{
+			{
+				Op: "add",
+				Component: &models.Component{
+					Header: &models.HeaderComponent{Content: "Fine", Size: 9},
+				},
+			},
+			{
+				Op: "add",
+				Component: &models.Component{
+					Paragraph: &models.PragraphComponent{Content: "Two at once"},
+					Divider:   &models.DividerComponent{Style: "wavy"},
+				},
+			},
+		},
+	})
+	var invalidBatchErrors handlers.ValidationErrorResponse
+	err = json.Unmarshal(invalidBatchResponse.Body.Bytes(), &invalidBatchErrors)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal validation response: %v", err)
+	}
+	assert.Equal(t, http.StatusUnprocessableEntity, invalidBatchResponse.Result().StatusCode)
+	invalidFields := []string{}
+	for _, fieldError := range invalidBatchErrors.Fields {
+		invalidFields = append(invalidFields, fieldError.Field)
+	}
+	assert.Equal(t, []string{
+		"operations[0].component.header.size",
+		"operations[1].component.divider.style",
+		"operations[1].component",
+	}, invalidFields)
+
+	// Test Get Userpage resolves post components
+	getUserpageResponse := PerformRequest(r, "GET", "/userpage", nil)
+	var ownUserpage models.Userpage
+	err = json.Unmarshal(getUserpageResponse.Body.Bytes(), &ownUserpage)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal userpage response: %v", err)
+	}
+	assert.Equal(t, http.StatusOK, getUserpageResponse.Result().StatusCode)
+	assert.Equal(t, len(userpageAfterMove.Components), len(ownUserpage.Components))
+	assert.NotNil(t, ownUserpage.Components[3].Post.Post)
+
+	// Test Stale Edit is rejected with the current page
+	staleMoveResponse := PerformRequest(r, "PUT", "/userpage/component/move", handlers.MoveComponentRequest{
+		Version:   userpageAfterHeader.Version,
+		PrevIndex: 0,
+		NewIndex:  2,
+	})
+	var currentUserpage models.Userpage
+	err = json.Unmarshal(staleMoveResponse.Body.Bytes(), &currentUserpage)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal userpage response: %v", err)
+	}
+	assert.Equal(t, http.StatusConflict, staleMoveResponse.Result().StatusCode)
+	assert.Equal(t, ownUserpage.Version, currentUserpage.Version)
+	assert.Equal(t, ownUserpage.Components[0].Header, currentUserpage.Components[0].Header)
+
+	// Test Component IDs are stable across updates and moves
+	for _, component := range currentUserpage.Components {
+		assert.False(t, component.ID.IsZero())
+	}
+	paragraphID := currentUserpage.Components[2].ID
+	dividerID := currentUserpage.Components[1].ID
+	postComponentID := currentUserpage.Components[3].ID
+	updateByIDResponse := PerformRequest(r, "PUT", "/userpage/component/update", handlers.UpdateComponentRequest{
+		Version:     currentUserpage.Version,
+		ComponentID: &paragraphID,
+		Component: models.Component{
+			Paragraph: &models.PragraphComponent{
+				Content: "Updated by **ID**, see [docs](https://example.com)",
+			},
+		},
+	})
+	var userpageAfterUpdate models.Userpage
+	err = json.Unmarshal(updateByIDResponse.Body.Bytes(), &userpageAfterUpdate)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal userpage response: %v", err)
+	}
+	assert.Equal(t, http.StatusOK, updateByIDResponse.Result().StatusCode)
+	assert.Equal(t, paragraphID, userpageAfterUpdate.Components[2].ID)
+	assert.Equal(t, "Updated by **ID**, see [docs](https://example.com)", userpageAfterUpdate.Components[2].Paragraph.Content)
+	assert.Equal(t,
+		`<p>Updated by <strong>ID</strong>, see <a href="https://example.com" rel="nofollow noopener noreferrer">docs</a></p>`,
+		userpageAfterUpdate.Components[2].Paragraph.ContentHTML)
+	unsafeLinkResponse := PerformRequest(r, "PUT", "/userpage/component/update", handlers.UpdateComponentRequest{
+		Version:     userpageAfterUpdate.Version,
+		ComponentID: &paragraphID,
+		Component: models.Component{
+			Paragraph: &models.PragraphComponent{
+				Content: "[click me](javascript:alert(document.cookie))",
+			},
+		},
+	})
+	assert.Equal(t, http.StatusUnprocessableEntity, unsafeLinkResponse.Result().StatusCode)
+	moveByIDResponse := PerformRequest(r, "PUT", "/userpage/component/move", handlers.MoveComponentRequest{
+		Version:           userpageAfterUpdate.Version,
+		ComponentID:       &dividerID,
+		BeforeComponentID: &postComponentID,
+	})
+	var userpageAfterMoveByID models.Userpage
+	err = json.Unmarshal(moveByIDResponse.Body.Bytes(), &userpageAfterMoveByID)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal userpage response: %v", err)
+	}
+	assert.Equal(t, http.StatusOK, moveByIDResponse.Result().StatusCode)
+	assert.Equal(t, paragraphID, userpageAfterMoveByID.Components[1].ID)
+	assert.Equal(t, dividerID, userpageAfterMoveByID.Components[2].ID)
+	assert.Equal(t, postComponentID, userpageAfterMoveByID.Components[3].ID)
+	unknownComponentID := primitive.NewObjectID()
+	deleteUnknownResponse := PerformRequest(r, "DELETE", "/userpage/component/delete", handlers.DeleteComponentRequest{
+		Version:     userpageAfterMoveByID.Version,
+		ComponentID: &unknownComponentID,
+	})
+	assert.Equal(t, http.StatusNotFound, deleteUnknownResponse.Result().StatusCode)
+
+	// Test Batch applies all operations in one edit
+	batchHeaderID := primitive.NewObjectID()
+	batchResponse := PerformRequest(r, "PUT", "/userpage/batch", handlers.BatchRequest{
+		Version: userpageAfterMoveByID.Version,
+		Operations: []handlers.BatchOperationRequest{
+			{
+				Op:    "add",
+				Index: 0,
+				Component: &models.Component{
+					ID:     batchHeaderID,
+					Header: &models.HeaderComponent{Content: "Draft", Size: models.HeaderComponentSizeSmall},
+				},
+			},
+			{
+				Op:          "update",
+				ComponentID: &batchHeaderID,
+				Component: &models.Component{
+					Header: &models.HeaderComponent{Content: "Batch Header", Size: models.HeaderComponentSizeSmall},
+				},
+			},
+		},
+	})
+	var userpageAfterBatch models.Userpage
+	err = json.Unmarshal(batchResponse.Body.Bytes(), &userpageAfterBatch)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal userpage response: %v", err)
+	}
+	assert.Equal(t, http.StatusOK, batchResponse.Result().StatusCode)
+	assert.Equal(t, userpageAfterMoveByID.Version+1, userpageAfterBatch.Version)
+	assert.Equal(t, len(userpageAfterMoveByID.Components)+1, len(userpageAfterBatch.Components))
+	assert.Equal(t, batchHeaderID, userpageAfterBatch.Components[0].ID)
+	assert.Equal(t, "Batch Header", userpageAfterBatch.Components[0].Header.Content)
+
+	// Test Batch with a failing operation changes nothing
+	failingBatchResponse := PerformRequest(r, "PUT", "/userpage/batch", handlers.BatchRequest{
+		Version: userpageAfterBatch.Version,
+		Operations: []handlers.BatchOperationRequest{
+			{Op: "delete", ComponentID: &batchHeaderID},
+			{Op: "delete", ComponentID: &unknownComponentID},
+		},
+	})
+	assert.Equal(t, http.StatusNotFound, failingBatchResponse.Result().StatusCode)
+	var userpageAfterFailedBatch models.Userpage
+	err = json.Unmarshal(PerformRequest(r, "GET", "/userpage", nil).Body.Bytes(), &userpageAfterFailedBatch)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal userpage response: %v", err)
+	}
+	assert.Equal(t, userpageAfterBatch.Version, userpageAfterFailedBatch.Version)
+	assert.Equal(t, batchHeaderID, userpageAfterFailedBatch.Components[0].ID)
+	unknownOpResponse := PerformRequest(r, "PUT", "/userpage/batch", handlers.BatchRequest{
+		Version:    userpageAfterBatch.Version,
+		Operations: []handlers.BatchOperationRequest{{Op: "rename"}},
+	})
+	assert.Equal(t, http.StatusBadRequest, unknownOpResponse.Result().StatusCode)
+	cleanupBatchResponse := PerformRequest(r, "PUT", "/userpage/batch", handlers.BatchRequest{
+		Version:    userpageAfterBatch.Version,
+		Operations: []handlers.BatchOperationRequest{{Op: "delete", ComponentID: &batchHeaderID}},
+	})
+	assert.Equal(t, http.StatusOK, cleanupBatchResponse.Result().StatusCode)
+	var userpageAfterCleanup models.Userpage
+	err = json.Unmarshal(cleanupBatchResponse.Body.Bytes(), &userpageAfterCleanup)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal userpage response: %v", err)
+	}
+
+	// Test Revisions list earlier versions, newest first
+	revisionsResponse := PerformRequest(r, "GET", "/userpage/revisions?limit=2", nil)
+	var revisions []models.UserpageRevision
+	err = json.Unmarshal(revisionsResponse.Body.Bytes(), &revisions)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal revisions response: %v", err)
+	}
+	assert.Equal(t, http.StatusOK, revisionsResponse.Result().StatusCode)
+	assert.Equal(t, 2, len(revisions))
+	assert.Equal(t, userpageAfterBatch.Version, revisions[0].Version)
+	assert.Equal(t, userpageAfterMoveByID.Version, revisions[1].Version)
+	assert.Nil(t, revisions[0].Components)
+
+	// Test Revision preview shows the page as it was
+	revisionResponse := PerformRequest(r, "GET", fmt.Sprintf("/userpage/revisions/%d", userpageAfterBatch.Version), nil)
+	var revision models.UserpageRevision
+	err = json.Unmarshal(revisionResponse.Body.Bytes(), &revision)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal revision response: %v", err)
+	}
+	assert.Equal(t, http.StatusOK, revisionResponse.Result().StatusCode)
+	assert.Equal(t, batchHeaderID, revision.Components[0].ID)
+	assert.NotNil(t, revision.Components[4].Post.Post)
+	missingRevisionResponse := PerformRequest(r, "GET", "/userpage/revisions/9999", nil)
+	assert.Equal(t, http.StatusNotFound, missingRevisionResponse.Result().StatusCode)
+
+	// Test Undo steps back through earlier edits
+	undoResponse := PerformRequest(r, "PUT", "/userpage/undo", handlers.UndoRequest{Version: userpageAfterCleanup.Version})
+	var userpageAfterUndo models.Userpage
+	err = json.Unmarshal(undoResponse.Body.Bytes(), &userpageAfterUndo)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal userpage response: %v", err)
+	}
+	assert.Equal(t, http.StatusOK, undoResponse.Result().StatusCode)
+	assert.Equal(t, batchHeaderID, userpageAfterUndo.Components[0].ID)
+	secondUndoResponse := PerformRequest(r, "PUT", "/userpage/undo", handlers.UndoRequest{Version: userpageAfterUndo.Version})
+	var userpageAfterSecondUndo models.Userpage
+	err = json.Unmarshal(secondUndoResponse.Body.Bytes(), &userpageAfterSecondUndo)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal userpage response: %v", err)
+	}
+	assert.Equal(t, http.StatusOK, secondUndoResponse.Result().StatusCode)
+	assert.Equal(t, len(userpageAfterMoveByID.Components), len(userpageAfterSecondUndo.Components))
+	assert.Equal(t, userpageAfterMoveByID.Components[0].ID, userpageAfterSecondUndo.Components[0].ID)
+
+	// Test Restore makes an earlier version current again
+	restoreResponse := PerformRequest(r, "PUT", "/userpage/restore", handlers.RestoreRevisionRequest{
+		Version:  userpageAfterSecondUndo.Version,
+		Revision: userpageAfterUndo.Version,
+	})
+	var userpageAfterRestore models.Userpage
+	err = json.Unmarshal(restoreResponse.Body.Bytes(), &userpageAfterRestore)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal userpage response: %v", err)
+	}
+	assert.Equal(t, http.StatusOK, restoreResponse.Result().StatusCode)
+	assert.Equal(t, batchHeaderID, userpageAfterRestore.Components[0].ID)
+	undoRestoreResponse := PerformRequest(r, "PUT", "/userpage/undo", handlers.UndoRequest{Version: userpageAfterRestore.Version})
+	var userpageAfterUndoRestore models.Userpage
+	err = json.Unmarshal(undoRestoreResponse.Body.Bytes(), &userpageAfterUndoRestore)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal userpage response: %v", err)
+	}
+	assert.Equal(t, http.StatusOK, undoRestoreResponse.Result().StatusCode)
+	assert.Equal(t, userpageAfterMoveByID.Components[0].ID, userpageAfterUndoRestore.Components[0].ID)
+	missingRestoreResponse := PerformRequest(r, "PUT", "/userpage/restore", handlers.RestoreRevisionRequest{
+		Version:  userpageAfterUndoRestore.Version,
+		Revision: 9999,
+	})
+	assert.Equal(t, http.StatusNotFound, missingRestoreResponse.Result().StatusCode)
+
+	// Test Get Post by ID
+	getPostResponse := PerformRequest(r, "GET", "/posts/"+addedPost.ID.Hex(), nil)
+	fetchedPost := models.Post{}
+	err = json.Unmarshal(getPostResponse.Body.Bytes(), &fetchedPost)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal post response: %v", err)
+	}
+	assert.Equal(t, http.StatusOK, getPostResponse.Result().StatusCode)
+	assert.Equal(t, addedPost.ID, fetchedPost.ID)
+	assert.NotNil(t, fetchedPost.Reactions)
+	assert.NotNil(t, fetchedPost.MyReaction)
+	missingPostResponse := PerformRequest(r, "GET", "/posts/"+primitive.NewObjectID().Hex(), nil)
+	assert.Equal(t, http.StatusNotFound, missingPostResponse.Result().StatusCode)
+
+	// Test Public Userpage resolves post components
+	publicUserpageResponse := PerformRequest(r, "GET", "/u/"+userName, nil)
+	var publicUserpage models.PublicUserpage
+	err = json.Unmarshal(publicUserpageResponse.Body.Bytes(), &publicUserpage)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal public userpage response: %v", err)
+	}
+	assert.Equal(t, http.StatusOK, publicUserpageResponse.Result().StatusCode)
+	assert.Equal(t, *user.ToProfile(), publicUserpage.User)
+	assert.Equal(t, len(userpageAfterMove.Components), len(publicUserpage.Components))
+	assert.NotNil(t, publicUserpage.Components[3].Post)
+	assert.NotNil(t, publicUserpage.Components[3].Post.Post)
+	assert.Equal(t, addedPost.Title, publicUserpage.Components[3].Post.Post.Title)
+	unknownUserpageResponse := PerformRequest(r, "GET", "/u/nobody-has-this-name", nil)
+	assert.Equal(t, http.StatusNotFound, unknownUserpageResponse.Result().StatusCode)
+
+	// Test Rendered Userpage and Feeds
+	htmlResponse := PerformRequest(r, "GET", "/pages/"+userName, nil)
+	assert.Equal(t, http.StatusOK, htmlResponse.Result().StatusCode)
+	assert.Equal(t, "text/html; charset=utf-8", htmlResponse.Header().Get("Content-Type"))
+	assert.Contains(t, htmlResponse.Body.String(), `href="`+addedPost.URL+`"`)
+	assert.Contains(t, htmlResponse.Body.String(), `<link rel="canonical" href="http://localhost:3000/pages/`+userName+`/main">`)
+	rssResponse := PerformRequest(r, "GET", "/pages/"+userName+"/main/rss.xml", nil)
+	assert.Equal(t, http.StatusOK, rssResponse.Result().StatusCode)
+	assert.Contains(t, rssResponse.Body.String(), "<link>"+addedPost.URL+"</link>")
+	atomResponse := PerformRequest(r, "GET", "/pages/"+userName+"/main/atom.xml", nil)
+	assert.Equal(t, http.StatusOK, atomResponse.Result().StatusCode)
+	assert.Contains(t, atomResponse.Body.String(), `<link href="`+addedPost.URL+`" rel="alternate"/>`)
+	missingFeedResponse := PerformRequest(r, "GET", "/pages/"+userName+"/no-such-page/rss.xml", nil)
+	assert.Equal(t, http.StatusNotFound, missingFeedResponse.Result().StatusCode)
+
+	// A post Tim added but no longer reacts to
+	unreactedPostResponse := PerformRequest(r, "PUT", "/user/posts/add", handlers.AddPostRequest{Post: models.Post{
+		Title:       "Added without a reaction",
+		Description: "Tim clears his reaction after adding it",
+		Author:      userName,
+		URL:         fmt.Sprintf("https://example.com/unreacted/%s", primitive.NewObjectID().Hex()),
+	}})
+	unreactedPost := models.Post{}
+	err = json.Unmarshal(unreactedPostResponse.Body.Bytes(), &unreactedPost)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal add post response: %v", err)
+	}
+	clearReactionResponse := PerformRequest(r, "DELETE", "/user/reactions/delete", handlers.ReactionRequest{
+		PostID:       unreactedPost.ID,
+		ReactionType: types.ReactionTypeAgree,
+	})
+	assert.Equal(t, http.StatusOK, clearReactionResponse.Result().StatusCode)
+
+	// Test Follow from a second user
+	followerLoginResponse := PerformRequest(r, "PUT", "/auth/login", handlers.LoginUserRequest{
+		Name:  "Ana",
+		Email: "Ana@Tom.com",
+	})
+	follower := models.User{}
+	err = json.Unmarshal(followerLoginResponse.Body.Bytes(), &follower)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal follower response: %v", err)
+	}
+	selfFollowResponse := PerformRequest(r, "PUT", "/user/follow", handlers.FollowRequest{UserID: follower.ID})
+	assert.Equal(t, http.StatusBadRequest, selfFollowResponse.Result().StatusCode)
+	followResponse := PerformRequest(r, "PUT", "/user/follow", handlers.FollowRequest{UserID: user.ID})
+	assert.Equal(t, http.StatusOK, followResponse.Result().StatusCode)
+
+	followersResponse := PerformRequest(r, "GET", "/users/"+user.ID.Hex()+"/followers", nil)
+	var followers []models.UserProfile
+	err = json.Unmarshal(followersResponse.Body.Bytes(), &followers)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal followers response: %v", err)
+	}
+	assert.Contains(t, followers, *follower.ToProfile())
+	followingResponse := PerformRequest(r, "GET", "/users/"+follower.ID.Hex()+"/following", nil)
+	var following []models.UserProfile
+	err = json.Unmarshal(followingResponse.Body.Bytes(), &following)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal following response: %v", err)
+	}
+	assert.Equal(t, []models.UserProfile{*user.ToProfile()}, following)
+
+	// Test Following Feed contains the posts of followed users
+	followingFeedResponse := PerformRequest(r, "GET", "/home?scope=following&limit=100", nil)
+	var followingPage models.PostPage
+	err = json.Unmarshal(followingFeedResponse.Body.Bytes(), &followingPage)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal following feed response: %v", err)
+	}
+	assert.Equal(t, http.StatusOK, followingFeedResponse.Result().StatusCode)
+	followingPostIDs := []string{}
+	for _, followingPost := range followingPage.Posts {
+		followingPostIDs = append(followingPostIDs, followingPost.ID.Hex())
+	}
+	assert.Contains(t, followingPostIDs, addedPost.ID.Hex())
+	assert.Contains(t, followingPostIDs, unreactedPost.ID.Hex())
+
+	unfollowResponse := PerformRequest(r, "DELETE", "/user/unfollow", handlers.FollowRequest{UserID: user.ID})
+	assert.Equal(t, http.StatusNoContent, unfollowResponse.Result().StatusCode)
+	followingFeedResponse = PerformRequest(r, "GET", "/home?scope=following", nil)
+	followingPage = models.PostPage{}
+	json.Unmarshal(followingFeedResponse.Body.Bytes(), &followingPage)
+	assert.Empty(t, followingPage.Posts)
+
+	// Test New Component Types, with a collection embedding a section of Tim's page
+	var followerUserpage models.Userpage
+	err = json.Unmarshal(PerformRequest(r, "GET", "/userpage", nil).Body.Bytes(), &followerUserpage)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal userpage response: %v", err)
+	}
+	timSectionID := userpageAfterUndoRestore.Components[0].ID
+	newTypesResponse := PerformRequest(r, "PUT", "/userpage/batch", handlers.BatchRequest{
+		Version: followerUserpage.Version,
+		Operations: []handlers.BatchOperationRequest{
+			{Op: "add", Index: 1, Component: &models.Component{
+				Quote: &models.QuoteComponent{Content: "Be excellent to each other.", Attribution: "Bill & Ted"},
+			}},
+			{Op: "add", Index: 2, Component: &models.Component{
+				LinkList: &models.LinkListComponent{Title: "Reading", Links: []models.Link{
+					{URL: "https://go.dev/doc/effective_go", Title: "Effective Go"},
+				}},
+			}},
+			{Op: "add", Index: 3, Component: &models.Component{
+				Image: &models.ImageComponent{URL: "https://example.com/cat.png", Caption: "A cat", Alt: "Cat"},
+			}},
+			{Op: "add", Index: 4, Component: &models.Component{
+				Collection: &models.CollectionComponent{UserID: user.ID, SectionID: timSectionID},
+			}},
+		},
+	})
+	var userpageWithNewTypes models.Userpage
+	err = json.Unmarshal(newTypesResponse.Body.Bytes(), &userpageWithNewTypes)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal userpage response: %v", err)
+	}
+	assert.Equal(t, http.StatusOK, newTypesResponse.Result().StatusCode)
+	assert.Equal(t, "Bill & Ted", userpageWithNewTypes.Components[1].Quote.Attribution)
+	assert.Equal(t, "Effective Go", userpageWithNewTypes.Components[2].LinkList.Links[0].Title)
+	assert.Equal(t, "A cat", userpageWithNewTypes.Components[3].Image.Caption)
+	collection := userpageWithNewTypes.Components[4].Collection
+	assert.False(t, collection.Missing)
+	assert.Equal(t, *user.ToProfile(), *collection.User)
+	assert.Equal(t, len(userpageAfterUndoRestore.Components), len(collection.Components))
+	assert.Equal(t, addedPost.ID, collection.Components[3].Post.Post.ID)
+
+	invalidNewTypesResponse := PerformRequest(r, "PUT", "/userpage/batch", handlers.BatchRequest{
+		Version: userpageWithNewTypes.Version,
+		Operations: []handlers.BatchOperationRequest{
+			{Op: "add", Component: &models.Component{
+				Image: &models.ImageComponent{URL: "javascript:alert(1)"},
+			}},
+			{Op: "add", Component: &models.Component{
+				Collection: &models.CollectionComponent{UserID: follower.ID, SectionID: primitive.NewObjectID()},
+			}},
+			{Op: "add", Component: &models.Component{
+				Collection: &models.CollectionComponent{UserID: user.ID, SectionID: primitive.NewObjectID()},
+			}},
+		},
+	})
+	var invalidNewTypesErrors handlers.ValidationErrorResponse
+	err = json.Unmarshal(invalidNewTypesResponse.Body.Bytes(), &invalidNewTypesErrors)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal validation response: %v", err)
+	}
+	assert.Equal(t, http.StatusUnprocessableEntity, invalidNewTypesResponse.Result().StatusCode)
+	invalidFields = []string{}
+	for _, fieldError := range invalidNewTypesErrors.Fields {
+		invalidFields = append(invalidFields, fieldError.Field)
+	}
+	assert.Equal(t, []string{
+		"operations[0].component.image.url",
+		"operations[1].component.collection.user_id",
+		"operations[2].component.collection.section_id",
+	}, invalidFields)
+
+	// Test Named Pages
+	var pages []models.UserpageSummary
+	err = json.Unmarshal(PerformRequest(r, "GET", "/userpages", nil).Body.Bytes(), &pages)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal userpages response: %v", err)
+	}
+	assert.Equal(t, 1, len(pages))
+	assert.Equal(t, models.DefaultUserpageSlug, pages[0].Slug)
+
+	createPageResponse := PerformRequest(r, "PUT", "/userpages/create", handlers.CreateUserpageRequest{
+		Slug:       "podcasts",
+		Title:      "Favourite podcasts",
+		Visibility: models.UserpageVisibilityPrivate,
+	})
+	var podcastsPage models.Userpage
+	err = json.Unmarshal(createPageResponse.Body.Bytes(), &podcastsPage)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal userpage response: %v", err)
+	}
+	assert.Equal(t, http.StatusCreated, createPageResponse.Result().StatusCode)
+	assert.Equal(t, "podcasts", podcastsPage.Slug)
+	assert.Empty(t, podcastsPage.Components)
+	duplicatePageResponse := PerformRequest(r, "PUT", "/userpages/create", handlers.CreateUserpageRequest{
+		Slug:       "podcasts",
+		Title:      "Podcasts again",
+		Visibility: models.UserpageVisibilityPublic,
+	})
+	assert.Equal(t, http.StatusUnprocessableEntity, duplicatePageResponse.Result().StatusCode)
+	invalidPageResponse := PerformRequest(r, "PUT", "/userpages/create", handlers.CreateUserpageRequest{
+		Slug:       "Not a slug",
+		Visibility: "friends",
+	})
+	assert.Equal(t, http.StatusUnprocessableEntity, invalidPageResponse.Result().StatusCode)
+
+	addToPageResponse := PerformRequest(r, "PUT", "/userpage/component/add", handlers.AddComponentRequest{
+		Page:    "podcasts",
+		Version: podcastsPage.Version,
+		Component: models.Component{
+			Header: &models.HeaderComponent{Content: "Podcasts", Size: models.HeaderComponentSizeLarge},
+		},
+	})
+	assert.Equal(t, http.StatusOK, addToPageResponse.Result().StatusCode)
+	err = json.Unmarshal(PerformRequest(r, "GET", "/userpage?page=podcasts", nil).Body.Bytes(), &podcastsPage)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal userpage response: %v", err)
+	}
+	assert.Equal(t, "Podcasts", podcastsPage.Components[0].Header.Content)
+	err = json.Unmarshal(PerformRequest(r, "GET", "/userpage", nil).Body.Bytes(), &followerUserpage)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal userpage response: %v", err)
+	}
+	assert.Equal(t, len(userpageWithNewTypes.Components), len(followerUserpage.Components))
+
+	reorderResponse := PerformRequest(r, "PUT", "/userpages/reorder", handlers.ReorderUserpagesRequest{
+		Pages: []string{"podcasts", models.DefaultUserpageSlug},
+	})
+	err = json.Unmarshal(reorderResponse.Body.Bytes(), &pages)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal userpages response: %v", err)
+	}
+	assert.Equal(t, http.StatusOK, reorderResponse.Result().StatusCode)
+	assert.Equal(t, []string{"podcasts", models.DefaultUserpageSlug}, []string{pages[0].Slug, pages[1].Slug})
+	invalidReorderResponse := PerformRequest(r, "PUT", "/userpages/reorder", handlers.ReorderUserpagesRequest{
+		Pages: []string{"podcasts", "podcasts"},
+	})
+	assert.Equal(t, http.StatusBadRequest, invalidReorderResponse.Result().StatusCode)
+
+	ownPrivatePageResponse := PerformRequest(r, "GET", "/u/Ana/podcasts", nil)
+	assert.Equal(t, http.StatusOK, ownPrivatePageResponse.Result().StatusCode)
+	publishResponse := PerformRequest(r, "PUT", "/userpages/update", handlers.UpdateUserpageRequest{
+		Page:       "podcasts",
+		Version:    podcastsPage.Version,
+		Slug:       "favourite-podcasts",
+		Title:      "Favourite podcasts",
+		Visibility: models.UserpageVisibilityPublic,
+	})
+	err = json.Unmarshal(publishResponse.Body.Bytes(), &podcastsPage)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal userpage response: %v", err)
+	}
+	assert.Equal(t, http.StatusOK, publishResponse.Result().StatusCode)
+	assert.Equal(t, "favourite-podcasts", podcastsPage.Slug)
+	publicPageResponse := PerformRequest(r, "GET", "/u/Ana/favourite-podcasts", nil)
+	var publicPage models.PublicUserpage
+	err = json.Unmarshal(publicPageResponse.Body.Bytes(), &publicPage)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal public userpage response: %v", err)
+	}
+	assert.Equal(t, http.StatusOK, publicPageResponse.Result().StatusCode)
+	assert.Equal(t, "Favourite podcasts", publicPage.Title)
+	assert.Equal(t, 2, len(publicPage.Pages))
+
+	// Test Page Visibility
+	unlistResponse := PerformRequest(r, "PUT", "/userpages/update", handlers.UpdateUserpageRequest{
+		Page:       "favourite-podcasts",
+		Version:    podcastsPage.Version,
+		Slug:       "favourite-podcasts",
+		Title:      "Favourite podcasts",
+		Visibility: models.UserpageVisibilityUnlisted,
+	})
+	err = json.Unmarshal(unlistResponse.Body.Bytes(), &podcastsPage)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal userpage response: %v", err)
+	}
+	assert.Equal(t, http.StatusOK, unlistResponse.Result().StatusCode)
+	oldShareToken := podcastsPage.ShareToken
+	assert.NotEmpty(t, oldShareToken)
+	rotateResponse := PerformRequest(r, "PUT", "/userpages/rotate-token", handlers.RotateShareTokenRequest{
+		Page:    "favourite-podcasts",
+		Version: podcastsPage.Version,
+	})
+	err = json.Unmarshal(rotateResponse.Body.Bytes(), &podcastsPage)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal userpage response: %v", err)
+	}
+	assert.Equal(t, http.StatusOK, rotateResponse.Result().StatusCode)
+	assert.NotEmpty(t, podcastsPage.ShareToken)
+	assert.NotEqual(t, oldShareToken, podcastsPage.ShareToken)
+	podcastsRevisionsResponse := PerformRequest(r, "GET", "/userpage/revisions?page=favourite-podcasts", nil)
+	var podcastsRevisions []models.UserpageRevision
+	err = json.Unmarshal(podcastsRevisionsResponse.Body.Bytes(), &podcastsRevisions)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal revisions response: %v", err)
+	}
+	for _, revision := range podcastsRevisions {
+		// Settings changes and rotated tokens are not kept as revisions
+		assert.NotEqual(t, podcastsPage.Version-1, revision.Version)
+	}
+	rotatePublicResponse := PerformRequest(r, "PUT", "/userpages/rotate-token", handlers.RotateShareTokenRequest{
+		Version: followerUserpage.Version,
+	})
+	assert.Equal(t, http.StatusBadRequest, rotatePublicResponse.Result().StatusCode)
+
+	// Seen by Tim
+	PerformRequest(r, "PUT", "/auth/login", loginRequest)
+	unlistedResponse := PerformRequest(r, "GET", "/u/Ana/favourite-podcasts", nil)
+	assert.Equal(t, http.StatusNotFound, unlistedResponse.Result().StatusCode)
+	oldTokenResponse := PerformRequest(r, "GET", "/u/Ana/favourite-podcasts?token="+oldShareToken, nil)
+	assert.Equal(t, http.StatusNotFound, oldTokenResponse.Result().StatusCode)
+	sharedResponse := PerformRequest(r, "GET", "/u/Ana/favourite-podcasts?token="+podcastsPage.ShareToken, nil)
+	var sharedPage models.PublicUserpage
+	err = json.Unmarshal(sharedResponse.Body.Bytes(), &sharedPage)
+	if err != nil {
+		t.Fatalf("Failed to unmarshal public userpage response: %v", err)
+	}
+	assert.Equal(t, http.StatusOK, sharedResponse.Result().StatusCode)
+	assert.Equal(t, "Podcasts", sharedPage.Components[0].Header.Content)
+	assert.Equal(t, 1, len(sharedPage.Pages))
+	PerformRequest(r, "PUT", "/auth/login", handlers.LoginUserRequest{Name: "Ana", Email: "Ana@Tom.com"})
+
+	renameDefaultResponse := PerformRequest(r, "PUT", "/userpages/update", handlers.UpdateUserpageRequest{
+		Version:    followerUserpage.Version,
+		Slug:       "renamed",
+		Title:      "Renamed",
+		Visibility: models.UserpageVisibilityPublic,
+	})
+	assert.Equal(t, http.StatusBadRequest, renameDefaultResponse.Result().StatusCode)
+	deleteDefaultResponse := PerformRequest(r, "DELETE", "/userpages/delete", handlers.DeleteUserpageRequest{
+		Page:    models.DefaultUserpageSlug,
+		Version: followerUserpage.Version,
+	})
+	assert.Equal(t, http.StatusBadRequest, deleteDefaultResponse.Result().StatusCode)
+	staleDeleteResponse := PerformRequest(r, "DELETE", "/userpages/delete", handlers.DeleteUserpageRequest{
+		Page:    "favourite-podcasts",
+		Version: podcastsPage.Version - 1,
+	})
+	assert.Equal(t, http.StatusConflict, staleDeleteResponse.Result().StatusCode)
+	deletePageResponse := PerformRequest(r, "DELETE", "/userpages/delete", handlers.DeleteUserpageRequest{
+		Page:    "favourite-podcasts",
+		Version: podcastsPage.Version,
+	})
+	assert.Equal(t, http.StatusNoContent, deletePageResponse.Result().StatusCode)
+	deletedPageResponse := PerformRequest(r, "GET", "/userpage?page=favourite-podcasts", nil)
+	assert.Equal(t, http.StatusNotFound, deletedPageResponse.Result().StatusCode)
 }
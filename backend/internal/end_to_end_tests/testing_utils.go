@@ -40,18 +40,25 @@ func SetupTestServer() *chi.Mux {
 	userRepo := repositories.NewUserRepository(client)
 	postRepo := repositories.NewPostRepository(client)
 	reactionRepo := repositories.NewReactionRepository(client)
+	addedPostRepo := repositories.NewAddedPostRepository(client)
 	userpageRepo := repositories.NewUserpageRepository(client)
+	userpageRevisionRepo := repositories.NewUserpageRevisionRepository(client)
+	followRepo := repositories.NewFollowRepository(client)
+	linkPreviewRepo := repositories.NewLinkPreviewRepository(client)
+	feedSnapshotRepo := repositories.NewFeedSnapshotRepository(client)
 
 	userService := services.NewUserService(userRepo, userpageRepo)
-	postService := services.NewPostService(postRepo, userRepo, reactionRepo)
-	reactionService := services.NewReactionService(reactionRepo)
-	userpageService := services.NewUserpageService(userpageRepo)
+	postService := services.NewPostService(postRepo, userRepo, reactionRepo, addedPostRepo, followRepo, linkPreviewRepo, feedSnapshotRepo)
+	reactionService := services.NewReactionService(reactionRepo, postRepo)
+	userpageService := services.NewUserpageService(userpageRepo, userpageRevisionRepo, userRepo, postRepo)
+	followService := services.NewFollowService(followRepo, userRepo)
 
 	// userHandler := handlers.NewUserHandler(userService)
 	postHandler := handlers.NewPostHandler(postService)
 	reactionHandler := handlers.NewReactionHandler(reactionService)
 	authHandler := handlers.NewAuthHandler(userService)
-	userpageHandler := handlers.NewUserpageHandler(userpageService)
+	userpageHandler := handlers.NewUserpageHandler(userpageService, "http://localhost:3000")
+	followHandler := handlers.NewFollowHandler(followService)
 	mockAuthHander := handlers.NewMockAuthHandler(userService)
 
 	r := chi.NewRouter()
@@ -78,12 +85,47 @@ func SetupTestServer() *chi.Mux {
 	})
 	r.Group(func(r chi.Router) {
 		r.Use(mockAuthHander.MockAuthMiddleWare)
+		r.Get("/userpage", userpageHandler.GetUserpage)
 		r.Put("/userpage/component/add", userpageHandler.AddComponent)
+		r.Put("/userpage/component/update", userpageHandler.UpdateComponent)
+		r.Delete("/userpage/component/delete", userpageHandler.DeleteComponent)
 		r.Put("/userpage/component/move", userpageHandler.MoveComponent)
+		r.Put("/userpage/batch", userpageHandler.Batch)
+		r.Get("/userpage/revisions", userpageHandler.GetRevisions)
+		r.Get("/userpage/revisions/{version}", userpageHandler.GetRevision)
+		r.Put("/userpage/undo", userpageHandler.Undo)
+		r.Put("/userpage/restore", userpageHandler.RestoreRevision)
+		r.Get("/userpages", userpageHandler.GetUserpages)
+		r.Put("/userpages/create", userpageHandler.CreateUserpage)
+		r.Put("/userpages/update", userpageHandler.UpdateUserpage)
+		r.Delete("/userpages/delete", userpageHandler.DeleteUserpage)
+		r.Put("/userpages/reorder", userpageHandler.ReorderUserpages)
+		r.Put("/userpages/rotate-token", userpageHandler.RotateShareToken)
 	})
-	r.Get("/home", postHandler.GetFeed)
-
-	_ = reactionHandler
+	r.Group(func(r chi.Router) {
+		r.Use(mockAuthHander.MockAuthMiddleWare)
+		r.Put("/user/reactions/add", reactionHandler.AddReaction)
+		r.Delete("/user/reactions/delete", reactionHandler.DeleteReaction)
+	})
+	r.Group(func(r chi.Router) {
+		r.Use(mockAuthHander.MockAuthMiddleWare)
+		r.Put("/user/follow", followHandler.Follow)
+		r.Delete("/user/unfollow", followHandler.Unfollow)
+	})
+	r.Group(func(r chi.Router) {
+		r.Use(mockAuthHander.MockOptionalAuthMiddleWare)
+		r.Get("/home", postHandler.GetFeed)
+		r.Get("/posts/{post_id}", postHandler.GetPost)
+		r.Get("/u/{username}", userpageHandler.GetPublicUserpage)
+		r.Get("/u/{username}/{slug}", userpageHandler.GetPublicUserpage)
+	})
+	r.Get("/posts/{post_id}/reactions", reactionHandler.GetPostReactions)
+	r.Get("/users/{user_id}/followers", followHandler.GetFollowers)
+	r.Get("/users/{user_id}/following", followHandler.GetFollowing)
+	r.Get("/pages/{username}", userpageHandler.GetUserpageHTML)
+	r.Get("/pages/{username}/{slug}", userpageHandler.GetUserpageHTML)
+	r.Get("/pages/{username}/{slug}/rss.xml", userpageHandler.GetUserpageRSS)
+	r.Get("/pages/{username}/{slug}/atom.xml", userpageHandler.GetUserpageAtom)
 
 	return r
 }
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"sane-discourse-backend/internal/services"
+
+	"github.com/go-chi/chi"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type FollowHandler struct {
+	followService *services.FollowService
+}
+
+func NewFollowHandler(followService *services.FollowService) *FollowHandler {
+	return &FollowHandler{
+		followService: followService,
+	}
+}
+
+type FollowRequest struct {
+	UserID primitive.ObjectID `json:"user_id" bson:"user_id"`
+}
+
+func (h *FollowHandler) Follow(w http.ResponseWriter, r *http.Request) {
+	var followRequest FollowRequest
+	if err := json.NewDecoder(r.Body).Decode(&followRequest); err != nil {
+		log.Printf("Follow: Invalid request body: %v", err)
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	userIDInterface := r.Context().Value("user_id")
+	userID, ok := userIDInterface.(primitive.ObjectID)
+	if !ok {
+		http.Error(w, "UserID should be of type primitive.ObjectID", http.StatusInternalServerError)
+		return
+	}
+
+	follow, err := h.followService.Follow(userID, followRequest.UserID)
+	if err != nil {
+		log.Printf("Follow: Request failed for input %+v: %v", followRequest, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(follow)
+}
+
+func (h *FollowHandler) Unfollow(w http.ResponseWriter, r *http.Request) {
+	var followRequest FollowRequest
+	if err := json.NewDecoder(r.Body).Decode(&followRequest); err != nil {
+		log.Printf("Unfollow: Invalid request body: %v", err)
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	userIDInterface := r.Context().Value("user_id")
+	userID, ok := userIDInterface.(primitive.ObjectID)
+	if !ok {
+		http.Error(w, "UserID should be of type primitive.ObjectID", http.StatusInternalServerError)
+		return
+	}
+
+	err := h.followService.Unfollow(userID, followRequest.UserID)
+	if err != nil {
+		log.Printf("Unfollow: Request failed for input %+v: %v", followRequest, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *FollowHandler) GetFollowers(w http.ResponseWriter, r *http.Request) {
+	userID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "user_id"))
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	followers, err := h.followService.GetFollowers(userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(followers)
+}
+
+func (h *FollowHandler) GetFollowing(w http.ResponseWriter, r *http.Request) {
+	userID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "user_id"))
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	following, err := h.followService.GetFollowing(userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(following)
+}
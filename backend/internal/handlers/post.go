@@ -2,11 +2,14 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"sane-discourse-backend/internal/models"
 	"sane-discourse-backend/internal/services"
+	"strconv"
 
+	"github.com/go-chi/chi"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -34,6 +37,19 @@ func dereferencePostSlice(posts []*models.Post) []models.Post {
 	return result
 }
 
+// parseLimit reads the optional limit query parameter, 0 if it is not set.
+func parseLimit(r *http.Request) (int, error) {
+	limitParam := r.URL.Query().Get("limit")
+	if limitParam == "" {
+		return 0, nil
+	}
+	limit, err := strconv.Atoi(limitParam)
+	if err != nil {
+		return 0, services.ErrInvalidLimit
+	}
+	return limit, nil
+}
+
 func (h *PostHandler) CreatePost(w http.ResponseWriter, r *http.Request) {
 	var createPostRequest CreatePostRequest
 	if err := json.NewDecoder(r.Body).Decode(&createPostRequest); err != nil {
@@ -86,6 +102,31 @@ func (h *PostHandler) AddPost(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(post)
 }
 
+func (h *PostHandler) GetPost(w http.ResponseWriter, r *http.Request) {
+	postID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "post_id"))
+	if err != nil {
+		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+	// Posts are public; the user ID is only set for logged-in viewers.
+	userID, _ := r.Context().Value("user_id").(primitive.ObjectID)
+
+	post, err := h.postService.GetPost(postID, userID)
+	if errors.Is(err, services.ErrPostNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("GetPost: Request failed for post %s: %v", postID.Hex(), err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(post)
+}
+
 // type GetUserPostsRequest struct {
 // 	UserId primitive.ObjectID `json:"user_id" bson:"user_id"`
 // }
@@ -107,7 +148,13 @@ func (h *PostHandler) GetUserPosts(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	posts, err := h.postService.GetUserPosts(userID)
+	limit, err := parseLimit(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	posts, err := h.postService.GetUserPosts(userID, r.URL.Query().Get("cursor"), limit)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
@@ -123,7 +170,25 @@ func (h *PostHandler) GetUserPosts(w http.ResponseWriter, r *http.Request) {
 // }
 
 func (h *PostHandler) GetFeed(w http.ResponseWriter, r *http.Request) {
-	posts, err := h.postService.GetFeed()
+	// The feed is public; the user ID is only set for logged-in viewers.
+	userID, _ := r.Context().Value("user_id").(primitive.ObjectID)
+
+	limit, err := parseLimit(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	posts, err := h.postService.GetFeed(userID, services.FeedOptions{
+		Scope:   r.URL.Query().Get("scope"),
+		Ranking: r.URL.Query().Get("sort"),
+		Cursor:  r.URL.Query().Get("cursor"),
+		Limit:   limit,
+	})
+	if errors.Is(err, services.ErrLoginRequired) {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
 	if err != nil {
 		log.Printf("GetFeed: Request failed: %v", err)
 		http.Error(w, err.Error(), http.StatusBadRequest)
@@ -1,7 +1,15 @@
 package handlers
 
 import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"sane-discourse-backend/internal/models"
 	"sane-discourse-backend/internal/services"
+	"sane-discourse-backend/pkg/types"
+
+	"github.com/go-chi/chi"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type ReactionHandler struct {
@@ -13,3 +21,100 @@ func NewReactionHandler(reactionService *services.ReactionService) *ReactionHand
 		reactionService: reactionService,
 	}
 }
+
+// ReactionRequest addresses one axis of a reaction either by a named
+// reaction type (e.g. "strong_agree") or by an explicit axis and value.
+type ReactionRequest struct {
+	PostID       primitive.ObjectID  `json:"post_id" bson:"post_id"`
+	ReactionType types.ReactionType  `json:"reaction_type,omitempty" bson:"reaction_type,omitempty"`
+	Axis         types.ReactionAxis  `json:"axis,omitempty" bson:"axis,omitempty"`
+	Value        types.ReactionValue `json:"value,omitempty" bson:"value,omitempty"`
+}
+
+func (h *ReactionHandler) AddReaction(w http.ResponseWriter, r *http.Request) {
+	var reactionRequest ReactionRequest
+	if err := json.NewDecoder(r.Body).Decode(&reactionRequest); err != nil {
+		log.Printf("AddReaction: Invalid request body: %v", err)
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	userIDInterface := r.Context().Value("user_id")
+	userID, ok := userIDInterface.(primitive.ObjectID)
+	if !ok {
+		http.Error(w, "UserID should be of type primitive.ObjectID", http.StatusInternalServerError)
+		return
+	}
+
+	var reaction *models.Reaction
+	var err error
+	if reactionRequest.ReactionType != "" {
+		reaction, err = h.reactionService.SetReactionType(userID, reactionRequest.PostID, reactionRequest.ReactionType)
+	} else {
+		reaction, err = h.reactionService.SetReaction(userID, reactionRequest.PostID, reactionRequest.Axis, reactionRequest.Value)
+	}
+	if err != nil {
+		log.Printf("AddReaction: Request failed for input %+v: %v", reactionRequest, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(reaction)
+}
+
+func (h *ReactionHandler) DeleteReaction(w http.ResponseWriter, r *http.Request) {
+	var reactionRequest ReactionRequest
+	if err := json.NewDecoder(r.Body).Decode(&reactionRequest); err != nil {
+		log.Printf("DeleteReaction: Invalid request body: %v", err)
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	userIDInterface := r.Context().Value("user_id")
+	userID, ok := userIDInterface.(primitive.ObjectID)
+	if !ok {
+		http.Error(w, "UserID should be of type primitive.ObjectID", http.StatusInternalServerError)
+		return
+	}
+
+	axis := reactionRequest.Axis
+	if reactionRequest.ReactionType != "" {
+		if !reactionRequest.ReactionType.IsValid() {
+			http.Error(w, services.ErrInvalidReactionType.Error(), http.StatusBadRequest)
+			return
+		}
+		axis = reactionRequest.ReactionType.Axis()
+	}
+
+	reaction, err := h.reactionService.RemoveReaction(userID, reactionRequest.PostID, axis)
+	if err != nil {
+		log.Printf("DeleteReaction: Request failed for input %+v: %v", reactionRequest, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(reaction)
+}
+
+func (h *ReactionHandler) GetPostReactions(w http.ResponseWriter, r *http.Request) {
+	postID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "post_id"))
+	if err != nil {
+		http.Error(w, "Invalid post ID", http.StatusBadRequest)
+		return
+	}
+
+	reactions, err := h.reactionService.GetPostReactions(postID)
+	if err != nil {
+		log.Printf("GetPostReactions: Request failed for post %s: %v", postID.Hex(), err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	json.NewEncoder(w).Encode(reactions)
+}
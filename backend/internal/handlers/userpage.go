@@ -2,31 +2,51 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
 	"sane-discourse-backend/internal/models"
 	"sane-discourse-backend/internal/services"
+	"strconv"
 
+	"github.com/go-chi/chi"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type UserpageHandler struct {
 	userpageService *services.UserpageService
+	// publicBaseURL is where clients reach the server, used for the links in
+	// rendered pages and feeds.
+	publicBaseURL string
 }
 
-func NewUserpageHandler(s *services.UserpageService) *UserpageHandler {
+func NewUserpageHandler(s *services.UserpageService, publicBaseURL string) *UserpageHandler {
 	return &UserpageHandler{
 		userpageService: s,
+		publicBaseURL:   publicBaseURL,
 	}
 }
 
+// Requests address components by ID. The index fields are the positional
+// addressing used before components had IDs and are only read when the
+// corresponding ID is omitted. Page is the slug of the page to edit and
+// defaults to the user's default page.
+
 type AddComponentRequest struct {
-	Index     int              `json:"index"`
-	Component models.Component `json:"component"`
+	Page              string              `json:"page,omitempty"`
+	Version           int                 `json:"version"`
+	BeforeComponentID *primitive.ObjectID `json:"before_component_id,omitempty"`
+	Index             int                 `json:"index"`
+	Component         models.Component    `json:"component"`
 }
 
 func (h *UserpageHandler) AddComponent(w http.ResponseWriter, r *http.Request) {
 	var addComponentRequest AddComponentRequest
-	json.NewDecoder(r.Body).Decode(&addComponentRequest)
+	err := json.NewDecoder(r.Body).Decode(&addComponentRequest)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	userIDInterface := r.Context().Value("user_id")
 	userID, ok := userIDInterface.(primitive.ObjectID)
@@ -37,25 +57,28 @@ func (h *UserpageHandler) AddComponent(w http.ResponseWriter, r *http.Request) {
 
 	userpage, err := h.userpageService.AddComponent(
 		userID,
-		addComponentRequest.Index,
+		addComponentRequest.Page,
+		addComponentRequest.Version,
+		services.ComponentPosition{
+			BeforeID: addComponentRequest.BeforeComponentID,
+			Index:    addComponentRequest.Index,
+		},
 		&addComponentRequest.Component,
 	)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
-	}
-	err = json.NewEncoder(w).Encode(*userpage)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeEditError(w, userpage, err)
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
+	writeJSON(w, http.StatusOK, userpage)
 }
 
 type MoveComponentRequest struct {
-	PrevIndex int `json:"prev_index" bson:"prev_index"`
-	NewIndex  int `json:"new_index" bson:"new_index"`
+	Page              string              `json:"page,omitempty" bson:"page,omitempty"`
+	Version           int                 `json:"version" bson:"version"`
+	ComponentID       *primitive.ObjectID `json:"component_id,omitempty" bson:"component_id,omitempty"`
+	BeforeComponentID *primitive.ObjectID `json:"before_component_id,omitempty" bson:"before_component_id,omitempty"`
+	PrevIndex         int                 `json:"prev_index" bson:"prev_index"`
+	NewIndex          int                 `json:"new_index" bson:"new_index"`
 }
 
 func (h *UserpageHandler) GetUserpage(w http.ResponseWriter, r *http.Request) {
@@ -66,28 +89,33 @@ func (h *UserpageHandler) GetUserpage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	userpage, err := h.userpageService.GetUserpage(userID)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	userpage, err := h.userpageService.GetUserpage(userID, r.URL.Query().Get("page"))
+	if errors.Is(err, services.ErrUserpageNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	err = json.NewEncoder(w).Encode(*userpage)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	writeJSON(w, http.StatusOK, userpage)
 }
 
 type UpdateComponentRequest struct {
-	Index     int              `json:"index"`
-	Component models.Component `json:"component"`
+	Page        string              `json:"page,omitempty"`
+	Version     int                 `json:"version"`
+	ComponentID *primitive.ObjectID `json:"component_id,omitempty"`
+	Index       int                 `json:"index"`
+	Component   models.Component    `json:"component"`
 }
 
 func (h *UserpageHandler) UpdateComponent(w http.ResponseWriter, r *http.Request) {
 	var updateComponentRequest UpdateComponentRequest
-	json.NewDecoder(r.Body).Decode(&updateComponentRequest)
+	err := json.NewDecoder(r.Body).Decode(&updateComponentRequest)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	userIDInterface := r.Context().Value("user_id")
 	userID, ok := userIDInterface.(primitive.ObjectID)
@@ -98,29 +126,35 @@ func (h *UserpageHandler) UpdateComponent(w http.ResponseWriter, r *http.Request
 
 	userpage, err := h.userpageService.UpdateComponent(
 		userID,
-		updateComponentRequest.Index,
+		updateComponentRequest.Page,
+		updateComponentRequest.Version,
+		services.ComponentRef{
+			ID:    updateComponentRequest.ComponentID,
+			Index: updateComponentRequest.Index,
+		},
 		&updateComponentRequest.Component,
 	)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
-		return
-	}
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	err = json.NewEncoder(w).Encode(*userpage)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeEditError(w, userpage, err)
 		return
 	}
+	writeJSON(w, http.StatusOK, userpage)
 }
 
 type DeleteComponentRequest struct {
-	Index int `json:"index"`
+	Page        string              `json:"page,omitempty"`
+	Version     int                 `json:"version"`
+	ComponentID *primitive.ObjectID `json:"component_id,omitempty"`
+	Index       int                 `json:"index"`
 }
 
 func (h *UserpageHandler) DeleteComponent(w http.ResponseWriter, r *http.Request) {
 	var deleteComponentRequest DeleteComponentRequest
-	json.NewDecoder(r.Body).Decode(&deleteComponentRequest)
+	err := json.NewDecoder(r.Body).Decode(&deleteComponentRequest)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	userIDInterface := r.Context().Value("user_id")
 	userID, ok := userIDInterface.(primitive.ObjectID)
@@ -131,24 +165,187 @@ func (h *UserpageHandler) DeleteComponent(w http.ResponseWriter, r *http.Request
 
 	userpage, err := h.userpageService.DeleteComponent(
 		userID,
-		deleteComponentRequest.Index,
+		deleteComponentRequest.Page,
+		deleteComponentRequest.Version,
+		services.ComponentRef{
+			ID:    deleteComponentRequest.ComponentID,
+			Index: deleteComponentRequest.Index,
+		},
+	)
+	if err != nil {
+		writeEditError(w, userpage, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, userpage)
+}
+
+func (h *UserpageHandler) MoveComponent(w http.ResponseWriter, r *http.Request) {
+	var moveComponentRequest MoveComponentRequest
+	err := json.NewDecoder(r.Body).Decode(&moveComponentRequest)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	userIDInterface := r.Context().Value("user_id")
+	userID, ok := userIDInterface.(primitive.ObjectID)
+	if !ok {
+		http.Error(w, "UserID should be of type primitive.ObjectID", http.StatusInternalServerError)
+		return
+	}
+
+	userpage, err := h.userpageService.MoveComponent(
+		userID,
+		moveComponentRequest.Page,
+		moveComponentRequest.Version,
+		services.ComponentRef{
+			ID:    moveComponentRequest.ComponentID,
+			Index: moveComponentRequest.PrevIndex,
+		},
+		services.ComponentPosition{
+			BeforeID: moveComponentRequest.BeforeComponentID,
+			Index:    moveComponentRequest.NewIndex,
+		},
 	)
+	if err != nil {
+		writeEditError(w, userpage, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, userpage)
+}
+
+// BatchOperationRequest is one operation of a batch edit. It takes the same
+// fields as the request of the single endpoint for that operation.
+type BatchOperationRequest struct {
+	Op                string              `json:"op"`
+	ComponentID       *primitive.ObjectID `json:"component_id,omitempty"`
+	BeforeComponentID *primitive.ObjectID `json:"before_component_id,omitempty"`
+	Index             int                 `json:"index"`
+	PrevIndex         int                 `json:"prev_index"`
+	NewIndex          int                 `json:"new_index"`
+	Component         *models.Component   `json:"component,omitempty"`
+}
+
+type BatchRequest struct {
+	Page       string                  `json:"page,omitempty"`
+	Version    int                     `json:"version"`
+	Operations []BatchOperationRequest `json:"operations"`
+}
+
+func (op BatchOperationRequest) toOperation() services.ComponentOperation {
+	operation := services.ComponentOperation{
+		Type:      services.ComponentOperationType(op.Op),
+		Ref:       services.ComponentRef{ID: op.ComponentID, Index: op.Index},
+		Position:  services.ComponentPosition{BeforeID: op.BeforeComponentID, Index: op.Index},
+		Component: op.Component,
+	}
+	if operation.Type == services.ComponentOperationMove {
+		operation.Ref.Index = op.PrevIndex
+		operation.Position.Index = op.NewIndex
+	}
+	return operation
+}
+
+func (h *UserpageHandler) Batch(w http.ResponseWriter, r *http.Request) {
+	var batchRequest BatchRequest
+	err := json.NewDecoder(r.Body).Decode(&batchRequest)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	err = json.NewEncoder(w).Encode(*userpage)
+
+	userIDInterface := r.Context().Value("user_id")
+	userID, ok := userIDInterface.(primitive.ObjectID)
+	if !ok {
+		http.Error(w, "UserID should be of type primitive.ObjectID", http.StatusInternalServerError)
+		return
+	}
+
+	operations := make([]services.ComponentOperation, len(batchRequest.Operations))
+	for i, op := range batchRequest.Operations {
+		operations[i] = op.toOperation()
+	}
+	userpage, err := h.userpageService.ApplyOperations(userID, batchRequest.Page, batchRequest.Version, operations)
+	if err != nil {
+		log.Printf("Batch: Request failed for input %+v: %v", batchRequest, err)
+		writeEditError(w, userpage, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, userpage)
+}
+
+func (h *UserpageHandler) GetRevisions(w http.ResponseWriter, r *http.Request) {
+	userIDInterface := r.Context().Value("user_id")
+	userID, ok := userIDInterface.(primitive.ObjectID)
+	if !ok {
+		http.Error(w, "UserID should be of type primitive.ObjectID", http.StatusInternalServerError)
+		return
+	}
+
+	limit, err := parseLimit(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	before := 0
+	if beforeParam := r.URL.Query().Get("before"); beforeParam != "" {
+		before, err = strconv.Atoi(beforeParam)
+		if err != nil {
+			http.Error(w, "before must be a version number", http.StatusBadRequest)
+			return
+		}
+	}
+
+	revisions, err := h.userpageService.GetRevisions(userID, r.URL.Query().Get("page"), before, limit)
+	if errors.Is(err, services.ErrUserpageNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	writeJSON(w, http.StatusOK, revisions)
 }
 
-func (h *UserpageHandler) MoveComponent(w http.ResponseWriter, r *http.Request) {
-	var moveComponentRequest MoveComponentRequest
-	json.NewDecoder(r.Body).Decode(&moveComponentRequest)
+func (h *UserpageHandler) GetRevision(w http.ResponseWriter, r *http.Request) {
+	userIDInterface := r.Context().Value("user_id")
+	userID, ok := userIDInterface.(primitive.ObjectID)
+	if !ok {
+		http.Error(w, "UserID should be of type primitive.ObjectID", http.StatusInternalServerError)
+		return
+	}
+
+	version, err := strconv.Atoi(chi.URLParam(r, "version"))
+	if err != nil {
+		http.Error(w, "version must be a number", http.StatusBadRequest)
+		return
+	}
+
+	revision, err := h.userpageService.GetRevision(userID, r.URL.Query().Get("page"), version)
+	if errors.Is(err, services.ErrRevisionNotFound) || errors.Is(err, services.ErrUserpageNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, http.StatusOK, revision)
+}
+
+type UndoRequest struct {
+	Page    string `json:"page,omitempty"`
+	Version int    `json:"version"`
+}
+
+func (h *UserpageHandler) Undo(w http.ResponseWriter, r *http.Request) {
+	var undoRequest UndoRequest
+	err := json.NewDecoder(r.Body).Decode(&undoRequest)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 
 	userIDInterface := r.Context().Value("user_id")
 	userID, ok := userIDInterface.(primitive.ObjectID)
@@ -157,20 +354,270 @@ func (h *UserpageHandler) MoveComponent(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	userpage, err := h.userpageService.MoveComponent(
-		userID,
-		moveComponentRequest.PrevIndex,
-		moveComponentRequest.NewIndex,
+	userpage, err := h.userpageService.Undo(userID, undoRequest.Page, undoRequest.Version)
+	if err != nil {
+		writeEditError(w, userpage, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, userpage)
+}
+
+type RestoreRevisionRequest struct {
+	Page     string `json:"page,omitempty"`
+	Version  int    `json:"version"`
+	Revision int    `json:"revision"`
+}
+
+func (h *UserpageHandler) RestoreRevision(w http.ResponseWriter, r *http.Request) {
+	var restoreRequest RestoreRevisionRequest
+	err := json.NewDecoder(r.Body).Decode(&restoreRequest)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	userIDInterface := r.Context().Value("user_id")
+	userID, ok := userIDInterface.(primitive.ObjectID)
+	if !ok {
+		http.Error(w, "UserID should be of type primitive.ObjectID", http.StatusInternalServerError)
+		return
+	}
+
+	userpage, err := h.userpageService.RestoreRevision(userID, restoreRequest.Page, restoreRequest.Version, restoreRequest.Revision)
+	if err != nil {
+		writeEditError(w, userpage, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, userpage)
+}
+
+func (h *UserpageHandler) GetPublicUserpage(w http.ResponseWriter, r *http.Request) {
+	// Pages are public; the user ID is only set for logged-in viewers, who
+	// can see their own private pages.
+	viewerID, _ := r.Context().Value("user_id").(primitive.ObjectID)
+
+	userpage, err := h.userpageService.GetPublicUserpage(
+		viewerID,
+		chi.URLParam(r, "username"),
+		chi.URLParam(r, "slug"),
+		r.URL.Query().Get("token"),
 	)
+	if errors.Is(err, services.ErrUserpageNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	err = json.NewEncoder(w).Encode(*userpage)
+	writeJSON(w, http.StatusOK, userpage)
+}
+
+func (h *UserpageHandler) GetUserpages(w http.ResponseWriter, r *http.Request) {
+	userIDInterface := r.Context().Value("user_id")
+	userID, ok := userIDInterface.(primitive.ObjectID)
+	if !ok {
+		http.Error(w, "UserID should be of type primitive.ObjectID", http.StatusInternalServerError)
+		return
+	}
+
+	userpages, err := h.userpageService.GetUserpages(userID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	writeJSON(w, http.StatusOK, userpages)
+}
+
+type CreateUserpageRequest struct {
+	Slug       string                    `json:"slug"`
+	Title      string                    `json:"title"`
+	Visibility models.UserpageVisibility `json:"visibility"`
+}
+
+func (h *UserpageHandler) CreateUserpage(w http.ResponseWriter, r *http.Request) {
+	var createRequest CreateUserpageRequest
+	if err := json.NewDecoder(r.Body).Decode(&createRequest); err != nil {
+		log.Printf("CreateUserpage: Invalid request body: %v", err)
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	userIDInterface := r.Context().Value("user_id")
+	userID, ok := userIDInterface.(primitive.ObjectID)
+	if !ok {
+		http.Error(w, "UserID should be of type primitive.ObjectID", http.StatusInternalServerError)
+		return
+	}
+
+	userpage, err := h.userpageService.CreateUserpage(userID, services.UserpageSettings{
+		Slug:       createRequest.Slug,
+		Title:      createRequest.Title,
+		Visibility: createRequest.Visibility,
+	})
+	if err != nil {
+		log.Printf("CreateUserpage: Request failed for input %+v: %v", createRequest, err)
+		writeEditError(w, userpage, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, *userpage)
+}
+
+// UpdateUserpageRequest changes the settings of the page Page. All settings
+// are replaced, so unchanged ones must be sent as they are.
+type UpdateUserpageRequest struct {
+	Page       string                    `json:"page"`
+	Version    int                       `json:"version"`
+	Slug       string                    `json:"slug"`
+	Title      string                    `json:"title"`
+	Visibility models.UserpageVisibility `json:"visibility"`
+}
+
+func (h *UserpageHandler) UpdateUserpage(w http.ResponseWriter, r *http.Request) {
+	var updateRequest UpdateUserpageRequest
+	if err := json.NewDecoder(r.Body).Decode(&updateRequest); err != nil {
+		log.Printf("UpdateUserpage: Invalid request body: %v", err)
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	userIDInterface := r.Context().Value("user_id")
+	userID, ok := userIDInterface.(primitive.ObjectID)
+	if !ok {
+		http.Error(w, "UserID should be of type primitive.ObjectID", http.StatusInternalServerError)
+		return
+	}
+
+	userpage, err := h.userpageService.UpdateUserpageSettings(userID, updateRequest.Page, updateRequest.Version, services.UserpageSettings{
+		Slug:       updateRequest.Slug,
+		Title:      updateRequest.Title,
+		Visibility: updateRequest.Visibility,
+	})
+	if err != nil {
+		log.Printf("UpdateUserpage: Request failed for input %+v: %v", updateRequest, err)
+		writeEditError(w, userpage, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, *userpage)
+}
+
+type DeleteUserpageRequest struct {
+	Page    string `json:"page"`
+	Version int    `json:"version"`
+}
+
+func (h *UserpageHandler) DeleteUserpage(w http.ResponseWriter, r *http.Request) {
+	var deleteRequest DeleteUserpageRequest
+	if err := json.NewDecoder(r.Body).Decode(&deleteRequest); err != nil {
+		log.Printf("DeleteUserpage: Invalid request body: %v", err)
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	userIDInterface := r.Context().Value("user_id")
+	userID, ok := userIDInterface.(primitive.ObjectID)
+	if !ok {
+		http.Error(w, "UserID should be of type primitive.ObjectID", http.StatusInternalServerError)
+		return
+	}
+
+	userpage, err := h.userpageService.DeleteUserpage(userID, deleteRequest.Page, deleteRequest.Version)
+	if err != nil {
+		log.Printf("DeleteUserpage: Request failed for input %+v: %v", deleteRequest, err)
+		writeEditError(w, userpage, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type ReorderUserpagesRequest struct {
+	Pages []string `json:"pages"`
+}
+
+func (h *UserpageHandler) ReorderUserpages(w http.ResponseWriter, r *http.Request) {
+	var reorderRequest ReorderUserpagesRequest
+	if err := json.NewDecoder(r.Body).Decode(&reorderRequest); err != nil {
+		log.Printf("ReorderUserpages: Invalid request body: %v", err)
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	userIDInterface := r.Context().Value("user_id")
+	userID, ok := userIDInterface.(primitive.ObjectID)
+	if !ok {
+		http.Error(w, "UserID should be of type primitive.ObjectID", http.StatusInternalServerError)
+		return
+	}
+
+	userpages, err := h.userpageService.ReorderUserpages(userID, reorderRequest.Pages)
 	if err != nil {
+		log.Printf("ReorderUserpages: Request failed for input %+v: %v", reorderRequest, err)
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
+	writeJSON(w, http.StatusOK, userpages)
+}
+
+type RotateShareTokenRequest struct {
+	Page    string `json:"page"`
+	Version int    `json:"version"`
+}
+
+func (h *UserpageHandler) RotateShareToken(w http.ResponseWriter, r *http.Request) {
+	var rotateRequest RotateShareTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&rotateRequest); err != nil {
+		log.Printf("RotateShareToken: Invalid request body: %v", err)
+		http.Error(w, "Invalid JSON", http.StatusBadRequest)
+		return
+	}
+
+	userIDInterface := r.Context().Value("user_id")
+	userID, ok := userIDInterface.(primitive.ObjectID)
+	if !ok {
+		http.Error(w, "UserID should be of type primitive.ObjectID", http.StatusInternalServerError)
+		return
+	}
+
+	userpage, err := h.userpageService.RotateShareToken(userID, rotateRequest.Page, rotateRequest.Version)
+	if err != nil {
+		log.Printf("RotateShareToken: Request failed for input %+v: %v", rotateRequest, err)
+		writeEditError(w, userpage, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, *userpage)
+}
+
+type ValidationErrorResponse struct {
+	Error  string                `json:"error"`
+	Fields []services.FieldError `json:"fields"`
+}
+
+// writeEditError answers a failed userpage edit. A stale edit gets 409 and
+// the current page, so the client can reapply its change on top of it.
+// Invalid components get 422 with one entry per invalid field.
+func writeEditError(w http.ResponseWriter, userpage *models.Userpage, err error) {
+	var validationErr *services.ValidationError
+	switch {
+	case errors.Is(err, services.ErrUserpageConflict):
+		writeJSON(w, http.StatusConflict, *userpage)
+	case errors.As(err, &validationErr):
+		writeJSON(w, http.StatusUnprocessableEntity, ValidationErrorResponse{
+			Error:  validationErr.Error(),
+			Fields: validationErr.Errors,
+		})
+	case errors.Is(err, services.ErrComponentNotFound), errors.Is(err, services.ErrRevisionNotFound),
+		errors.Is(err, services.ErrUserpageNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	err := json.NewEncoder(w).Encode(body)
+	if err != nil {
+		log.Printf("writeJSON: Failed to encode response: %v", err)
+	}
 }
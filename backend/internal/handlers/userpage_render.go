@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"sane-discourse-backend/internal/models"
+	"sane-discourse-backend/internal/render"
+	"sane-discourse-backend/internal/services"
+
+	"github.com/go-chi/chi"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// The rendered pages and feeds are meant for search engines, feed readers
+// and browsers without JavaScript, so they are always rendered for an
+// anonymous viewer: only public pages, and unlisted ones with their token.
+// Their links are built from the configured public base URL rather than
+// the request's Host header, which clients and proxies can set to anything
+// and would end up in cached pages and feeds.
+
+func (h *UserpageHandler) GetUserpageHTML(w http.ResponseWriter, r *http.Request) {
+	h.renderUserpage(w, r, render.HTML, "text/html; charset=utf-8")
+}
+
+func (h *UserpageHandler) GetUserpageRSS(w http.ResponseWriter, r *http.Request) {
+	h.renderUserpage(w, r, render.RSS, "application/rss+xml; charset=utf-8")
+}
+
+func (h *UserpageHandler) GetUserpageAtom(w http.ResponseWriter, r *http.Request) {
+	h.renderUserpage(w, r, render.Atom, "application/atom+xml; charset=utf-8")
+}
+
+func (h *UserpageHandler) renderUserpage(w http.ResponseWriter, r *http.Request, renderPage func(io.Writer, render.Page) error, contentType string) {
+	username := chi.URLParam(r, "username")
+	token := r.URL.Query().Get("token")
+	userpage, err := h.userpageService.GetPublicUserpage(primitive.NilObjectID, username, chi.URLParam(r, "slug"), token)
+	if errors.Is(err, services.ErrUserpageNotFound) {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	page := render.Page{
+		Userpage: userpage,
+		BaseURL:  h.publicBaseURL + "/pages/" + url.PathEscape(username),
+	}
+	if userpage.Visibility == models.UserpageVisibilityUnlisted {
+		page.ShareToken = token
+	}
+	w.Header().Set("Content-Type", contentType)
+	if err = renderPage(w, page); err != nil {
+		log.Printf("renderUserpage: Failed to render page %s/%s: %v", username, userpage.Slug, err)
+		http.Error(w, "Failed to render page", http.StatusInternalServerError)
+	}
+}
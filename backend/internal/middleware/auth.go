@@ -18,9 +18,29 @@ func AuthMiddleWare(next http.Handler) http.Handler {
 		userID, ok := session.Values["user_id"]
 		if !ok {
 			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
 		}
 		userID = userID.(primitive.ObjectID)
 		ctx := context.WithValue(r.Context(), "user_id", userID)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+// OptionalAuthMiddleWare sets the user ID for logged-in users and lets
+// anonymous requests through unchanged.
+func OptionalAuthMiddleWare(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		session, err := gothic.Store.Get(r, "auth-session")
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		userID, ok := session.Values["user_id"].(primitive.ObjectID)
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+		ctx := context.WithValue(r.Context(), "user_id", userID)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
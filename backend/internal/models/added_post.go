@@ -0,0 +1,21 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AddedPost records that a user added a post. A post can be added by many
+// users, since adding a link that already is a post adds that post.
+type AddedPost struct {
+	ID     primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UserID primitive.ObjectID `json:"user_id" bson:"user_id"`
+	PostID primitive.ObjectID `json:"post_id" bson:"post_id"`
+}
+
+func NewAddedPost(userID, postID primitive.ObjectID) *AddedPost {
+	return &AddedPost{
+		ID:     primitive.NewObjectID(),
+		UserID: userID,
+		PostID: postID,
+	}
+}
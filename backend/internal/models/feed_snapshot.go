@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// FeedSnapshotTTL is how long the later pages of a feed can be loaded after
+// its second page.
+const FeedSnapshotTTL = time.Hour
+
+// FeedSnapshot is the ranking of a feed at the time its second page was
+// loaded, without the posts the first page showed. Later pages are read from
+// it, so posts whose reactions change in between neither move to another
+// page nor show up twice.
+type FeedSnapshot struct {
+	ID        primitive.ObjectID   `bson:"_id"`
+	PostIDs   []primitive.ObjectID `bson:"post_ids"`
+	CreatedAt time.Time            `bson:"created_at"`
+}
+
+func NewFeedSnapshot(postIDs []primitive.ObjectID) *FeedSnapshot {
+	return &FeedSnapshot{
+		ID:        primitive.NewObjectID(),
+		PostIDs:   postIDs,
+		CreatedAt: time.Now(),
+	}
+}
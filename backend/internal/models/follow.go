@@ -0,0 +1,20 @@
+package models
+
+import (
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Follow records that FollowerID follows FolloweeID.
+type Follow struct {
+	ID         primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	FollowerID primitive.ObjectID `json:"follower_id" bson:"follower_id"`
+	FolloweeID primitive.ObjectID `json:"followee_id" bson:"followee_id"`
+}
+
+func NewFollow(followerID, followeeID primitive.ObjectID) *Follow {
+	return &Follow{
+		ID:         primitive.NewObjectID(),
+		FollowerID: followerID,
+		FolloweeID: followeeID,
+	}
+}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// LinkPreviewTTL is how long scraped metadata is reused before a link is
+// fetched again.
+const LinkPreviewTTL = 24 * time.Hour
+
+// LinkPreview caches the post scraped from a link, keyed by the link's
+// canonical URL. The post's ID is not stored.
+type LinkPreview struct {
+	CanonicalURL string    `bson:"_id"`
+	Post         Post      `bson:"post"`
+	FetchedAt    time.Time `bson:"fetched_at"`
+}
+
+func NewLinkPreview(canonicalURL string, post Post) *LinkPreview {
+	post.ID = primitive.NilObjectID
+	return &LinkPreview{
+		CanonicalURL: canonicalURL,
+		Post:         post,
+		FetchedAt:    time.Now(),
+	}
+}
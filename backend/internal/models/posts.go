@@ -2,6 +2,8 @@
 package models
 
 import (
+	"sane-discourse-backend/pkg/types"
+
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -12,11 +14,17 @@ type Post struct {
 	ThumbnailURL string             `json:"thumbnail_url" bson:"thumbnail_url"`
 	SiteName     string             `json:"site_name" bson:"site_name"`
 	URL          string             `json:"url" bson:"url"`
-	Type         string             `json:"type" bson:"type"`
+	CanonicalURL string             `json:"canonical_url" bson:"canonical_url,omitempty"`
+	Type         types.PostType     `json:"type" bson:"type"`
 	Author       string             `json:"author" bson:"author" validate:"required,max=50"`
+
+	// Reactions is maintained by ReactionRepository, MyReaction is filled in
+	// per viewer and never stored.
+	Reactions  *ReactionSummary `json:"reactions,omitempty" bson:"reactions,omitempty"`
+	MyReaction *Reaction        `json:"my_reaction,omitempty" bson:"-"`
 }
 
-func NewPost(title, description, thumbnailURL, siteName, url, postType, author string) *Post {
+func NewPost(title, description, thumbnailURL, siteName, url, canonicalURL string, postType types.PostType, author string) *Post {
 	return &Post{
 		ID:           primitive.NewObjectID(),
 		Title:        title,
@@ -24,7 +32,14 @@ func NewPost(title, description, thumbnailURL, siteName, url, postType, author s
 		ThumbnailURL: thumbnailURL,
 		SiteName:     siteName,
 		URL:          url,
+		CanonicalURL: canonicalURL,
 		Type:         postType,
 		Author:       author,
 	}
 }
+
+// PostPage is one page of a post listing. NextCursor is empty on the last page.
+type PostPage struct {
+	Posts      []Post `json:"posts"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
@@ -6,18 +6,92 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// Reaction holds one user's reaction to one post, with a signed value per axis.
 type Reaction struct {
-	ID           primitive.ObjectID `json:"id" bson:"_id,omitempty"`
-	ReactionType types.ReactionType `json:"reaction_type" bson:"reaction_type"`
-	UserID       primitive.ObjectID `json:"user_id" bson:"user_id"`
-	PostID       primitive.ObjectID `json:"post_id" bson:"post_id"`
+	ID         primitive.ObjectID  `json:"id" bson:"_id,omitempty"`
+	UserID     primitive.ObjectID  `json:"user_id" bson:"user_id"`
+	PostID     primitive.ObjectID  `json:"post_id" bson:"post_id"`
+	Agreement  types.ReactionValue `json:"agreement" bson:"agreement"`
+	Importance types.ReactionValue `json:"importance" bson:"importance"`
+	Vote       types.ReactionValue `json:"vote" bson:"vote"`
 }
 
 func NewReaction(reactionType types.ReactionType, userID, postID primitive.ObjectID) *Reaction {
-	return &Reaction{
-		ID:           primitive.NewObjectID(),
-		ReactionType: reactionType,
-		UserID:       userID,
-		PostID:       postID,
+	reaction := &Reaction{
+		ID:     primitive.NewObjectID(),
+		UserID: userID,
+		PostID: postID,
+	}
+	reaction.Set(reactionType.Axis(), reactionType.Value())
+	return reaction
+}
+
+func (r *Reaction) Get(axis types.ReactionAxis) types.ReactionValue {
+	switch axis {
+	case types.ReactionAxisAgreement:
+		return r.Agreement
+	case types.ReactionAxisImportance:
+		return r.Importance
+	case types.ReactionAxisVote:
+		return r.Vote
+	}
+	return types.ReactionValueNone
+}
+
+func (r *Reaction) Set(axis types.ReactionAxis, value types.ReactionValue) {
+	switch axis {
+	case types.ReactionAxisAgreement:
+		r.Agreement = value
+	case types.ReactionAxisImportance:
+		r.Importance = value
+	case types.ReactionAxisVote:
+		r.Vote = value
+	}
+}
+
+// IsEmpty reports whether the reaction has no value on any axis.
+func (r *Reaction) IsEmpty() bool {
+	return r.Agreement == types.ReactionValueNone &&
+		r.Importance == types.ReactionValueNone &&
+		r.Vote == types.ReactionValueNone
+}
+
+// ReactionTypes lists the named reaction types this reaction expresses.
+func (r *Reaction) ReactionTypes() []types.ReactionType {
+	reactionTypes := []types.ReactionType{}
+	for _, axis := range types.ReactionAxes {
+		if reactionType, ok := types.ReactionTypeFor(axis, r.Get(axis)); ok {
+			reactionTypes = append(reactionTypes, reactionType)
+		}
+	}
+	return reactionTypes
+}
+
+// ReactionSummary aggregates all reactions on a post: how many users
+// reacted, how often each reaction type was used and the net score per axis.
+type ReactionSummary struct {
+	Total  int                        `json:"total" bson:"total"`
+	Counts map[types.ReactionType]int `json:"counts" bson:"counts"`
+	Scores map[types.ReactionAxis]int `json:"scores" bson:"scores"`
+}
+
+func NewReactionSummary() *ReactionSummary {
+	return &ReactionSummary{
+		Counts: map[types.ReactionType]int{},
+		Scores: map[types.ReactionAxis]int{
+			types.ReactionAxisAgreement:  0,
+			types.ReactionAxisImportance: 0,
+			types.ReactionAxisVote:       0,
+		},
+	}
+}
+
+func (s *ReactionSummary) Add(reaction Reaction) {
+	s.Total++
+	for _, reactionType := range reaction.ReactionTypes() {
+		s.Counts[reactionType]++
+	}
+	for _, axis := range types.ReactionAxes {
+		s.Scores[axis] += int(reaction.Get(axis))
 	}
 }
@@ -42,3 +42,16 @@ type UserPublic struct {
 	Username string             `json:"username"`
 	Email    string             `json:"email"`
 }
+
+func (u *User) ToProfile() *UserProfile {
+	return &UserProfile{
+		ID:       u.ID,
+		Username: u.Username,
+	}
+}
+
+// UserProfile is what other users may see about a user.
+type UserProfile struct {
+	ID       primitive.ObjectID `json:"id"`
+	Username string             `json:"username"`
+}
@@ -1,6 +1,8 @@
 package models
 
 import (
+	"time"
+
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -19,14 +21,26 @@ const HeaderComponentSizeLarge HeaderComponentSize = 1
 
 type DeviderType string
 
-const RegularDevider = "regular"
+const RegularDevider DeviderType = "regular"
+
+func (t DeviderType) IsValid() bool {
+	return t == RegularDevider
+}
 
 type Component struct {
+	// ID identifies the component within its page. It is assigned by the
+	// server and stays the same when the component is updated or moved.
+	ID primitive.ObjectID `json:"id" bson:"id,omitempty"`
+
 	// Only one of these will be non-nil
-	Header    *HeaderComponent   `json:"header,omitempty" bson:"header,omitempty"`
-	Post      *PostComponent     `json:"post,omitempty" bson:"post,omitempty"`
-	Paragraph *PragraphComponent `json:"paragraph,omitempty" bson:"paragraph,omitempty"`
-	Divider   *DividerComponent  `json:"divider,omitempty" bson:"divider,omitempty"`
+	Header     *HeaderComponent     `json:"header,omitempty" bson:"header,omitempty"`
+	Post       *PostComponent       `json:"post,omitempty" bson:"post,omitempty"`
+	Paragraph  *PragraphComponent   `json:"paragraph,omitempty" bson:"paragraph,omitempty"`
+	Divider    *DividerComponent    `json:"divider,omitempty" bson:"divider,omitempty"`
+	Quote      *QuoteComponent      `json:"quote,omitempty" bson:"quote,omitempty"`
+	LinkList   *LinkListComponent   `json:"link_list,omitempty" bson:"link_list,omitempty"`
+	Image      *ImageComponent      `json:"image,omitempty" bson:"image,omitempty"`
+	Collection *CollectionComponent `json:"collection,omitempty" bson:"collection,omitempty"`
 }
 
 type HeaderComponent struct {
@@ -37,26 +51,206 @@ type HeaderComponent struct {
 type PostComponent struct {
 	PostID primitive.ObjectID `json:"post_id" bson:"post_id"`
 	Size   PostComponentSize  `json:"size" bson:"size"`
+
+	// Post is the referenced post, resolved when the page is served.
+	// Missing is set instead if the post no longer exists.
+	Post    *Post `json:"post,omitempty" bson:"-"`
+	Missing bool  `json:"missing,omitempty" bson:"-"`
 }
 
+// PragraphComponent holds Markdown. ContentHTML is the sanitized HTML
+// rendering, filled in when the page is served.
 type PragraphComponent struct {
-	Content string `json:"content" bson:"content"`
+	Content     string `json:"content" bson:"content"`
+	ContentHTML string `json:"content_html,omitempty" bson:"-"`
 }
 
 type DividerComponent struct {
 	Style DeviderType `json:"style" bson:"style"`
 }
 
+type QuoteComponent struct {
+	Content     string `json:"content" bson:"content"`
+	Attribution string `json:"attribution" bson:"attribution"`
+}
+
+type Link struct {
+	URL   string `json:"url" bson:"url"`
+	Title string `json:"title" bson:"title"`
+}
+
+type LinkListComponent struct {
+	Title string `json:"title" bson:"title"`
+	Links []Link `json:"links" bson:"links"`
+}
+
+type ImageComponent struct {
+	URL     string `json:"url" bson:"url"`
+	Caption string `json:"caption" bson:"caption"`
+	Alt     string `json:"alt" bson:"alt"`
+}
+
+// CollectionComponent embeds a section of another user's page: the header
+// component SectionID and everything below it up to the next header of the
+// same or a larger size.
+type CollectionComponent struct {
+	UserID    primitive.ObjectID `json:"user_id" bson:"user_id"`
+	SectionID primitive.ObjectID `json:"section_id" bson:"section_id"`
+
+	// User and Components are resolved when the page is served, one level
+	// deep: collections inside the section are not resolved again.
+	// Missing is set instead if the section no longer exists.
+	User       *UserProfile `json:"user,omitempty" bson:"-"`
+	Components []Component  `json:"components,omitempty" bson:"-"`
+	Missing    bool         `json:"missing,omitempty" bson:"-"`
+}
+
+// DefaultUserpageSlug is the slug of the page every user gets on signup.
+// It can't be renamed or deleted. Pages stored before users could have
+// several pages have no slug and are the default page.
+const DefaultUserpageSlug = "main"
+
+const DefaultUserpageTitle = "My Page"
+
+type UserpageVisibility string
+
+// Public pages can be seen by anyone and are listed on the user's profile.
+// Unlisted pages can be seen by anyone who has the link with the page's
+// share token. Private pages can only be seen by their owner.
+const (
+	UserpageVisibilityPublic   UserpageVisibility = "public"
+	UserpageVisibilityUnlisted UserpageVisibility = "unlisted"
+	UserpageVisibilityPrivate  UserpageVisibility = "private"
+)
+
+func (v UserpageVisibility) IsValid() bool {
+	return v == UserpageVisibilityPublic || v == UserpageVisibilityUnlisted || v == UserpageVisibilityPrivate
+}
+
 type Userpage struct {
-	ID         primitive.ObjectID `json:"id" bson:"_id,omitempty"`
-	UserID     primitive.ObjectID `json:"user_id" bson:"user_id"`
-	Components []Component        `json:"components" bson:"components"`
+	ID     primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UserID primitive.ObjectID `json:"user_id" bson:"user_id"`
+	// Slug names the page in URLs and is unique among the user's pages.
+	Slug       string             `json:"slug" bson:"slug,omitempty"`
+	Title      string             `json:"title" bson:"title,omitempty"`
+	Visibility UserpageVisibility `json:"visibility" bson:"visibility,omitempty"`
+	// ShareToken is the secret part of the link to an unlisted page. It is
+	// only set while the page is unlisted and only shown to the owner.
+	ShareToken string `json:"share_token,omitempty" bson:"share_token,omitempty"`
+	// Position orders the user's pages, lowest first.
+	Position   int         `json:"position" bson:"position"`
+	Components []Component `json:"components" bson:"components"`
+	// Version is incremented on every change. Edits must carry the version
+	// they were based on so concurrent edits don't overwrite each other.
+	Version   int       `json:"version" bson:"version"`
+	UpdatedAt time.Time `json:"updated_at" bson:"updated_at,omitempty"`
+	// UndoVersion is the version that undoing the last edit goes back to,
+	// nil if there is nothing to undo.
+	UndoVersion *int `json:"-" bson:"undo_version,omitempty"`
 }
 
+// NewUserpage creates the user's default page.
 func NewUserpage(components []Component, userID primitive.ObjectID) *Userpage {
-	return &Userpage{
+	return NewNamedUserpage(components, userID, DefaultUserpageSlug, DefaultUserpageTitle, UserpageVisibilityPublic)
+}
+
+func NewNamedUserpage(components []Component, userID primitive.ObjectID, slug string, title string, visibility UserpageVisibility) *Userpage {
+	userpage := &Userpage{
 		ID:         primitive.NewObjectID(),
 		UserID:     userID,
+		Slug:       slug,
+		Title:      title,
+		Visibility: visibility,
 		Components: components,
 	}
+	userpage.EnsureComponentIDs()
+	return userpage
+}
+
+// ApplyDefaults fills in the fields that pages stored before users could
+// have several pages lack.
+func (u *Userpage) ApplyDefaults() {
+	if u.Slug == "" {
+		u.Slug = DefaultUserpageSlug
+	}
+	if u.Title == "" {
+		u.Title = DefaultUserpageTitle
+	}
+	if u.Visibility == "" {
+		u.Visibility = UserpageVisibilityPublic
+	}
+}
+
+func (u *Userpage) IsDefault() bool {
+	return u.Slug == DefaultUserpageSlug
+}
+
+func (u *Userpage) ToSummary() *UserpageSummary {
+	return &UserpageSummary{
+		ID:         u.ID,
+		Slug:       u.Slug,
+		Title:      u.Title,
+		Visibility: u.Visibility,
+		Position:   u.Position,
+		Version:    u.Version,
+		UpdatedAt:  u.UpdatedAt,
+	}
+}
+
+// UserpageSummary describes a page without its components, for listing a
+// user's pages.
+type UserpageSummary struct {
+	ID         primitive.ObjectID `json:"id"`
+	Slug       string             `json:"slug"`
+	Title      string             `json:"title"`
+	Visibility UserpageVisibility `json:"visibility"`
+	Position   int                `json:"position"`
+	Version    int                `json:"version"`
+	UpdatedAt  time.Time          `json:"updated_at"`
+}
+
+// EnsureComponentIDs gives every component that has no ID yet a new one,
+// and reports whether any component was changed.
+func (u *Userpage) EnsureComponentIDs() bool {
+	changed := false
+	for i := range u.Components {
+		if u.Components[i].ID.IsZero() {
+			u.Components[i].ID = primitive.NewObjectID()
+			changed = true
+		}
+	}
+	return changed
+}
+
+// Section returns the header component with the given ID and the components
+// below it, up to the next header of the same or a larger size.
+func (u *Userpage) Section(headerID primitive.ObjectID) ([]Component, bool) {
+	for i, component := range u.Components {
+		if component.ID != headerID || component.Header == nil {
+			continue
+		}
+		end := i + 1
+		for end < len(u.Components) {
+			next := u.Components[end].Header
+			// Smaller size values are larger headers
+			if next != nil && next.Size <= component.Header.Size {
+				break
+			}
+			end++
+		}
+		return u.Components[i:end], true
+	}
+	return nil, false
+}
+
+// PublicUserpage is the read-only view of a userpage that others may see.
+// Pages lists the user's public pages, so readers can move between them.
+type PublicUserpage struct {
+	User       UserProfile        `json:"user"`
+	Slug       string             `json:"slug"`
+	Title      string             `json:"title"`
+	Visibility UserpageVisibility `json:"visibility"`
+	Components []Component        `json:"components"`
+	Pages      []UserpageSummary  `json:"pages"`
+	UpdatedAt  time.Time          `json:"updated_at"`
 }
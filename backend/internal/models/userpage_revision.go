@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// UserpageRevision is a snapshot of a userpage as it was at one version.
+// A revision is stored whenever an edit replaces that version.
+type UserpageRevision struct {
+	ID         primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	UserpageID primitive.ObjectID `json:"userpage_id" bson:"userpage_id"`
+	Version    int                `json:"version" bson:"version"`
+	// UpdatedAt is when the page reached this version, zero if unknown
+	UpdatedAt   time.Time   `json:"updated_at" bson:"updated_at"`
+	UndoVersion *int        `json:"-" bson:"undo_version,omitempty"`
+	Components  []Component `json:"components,omitempty" bson:"components"`
+}
+
+func NewUserpageRevision(userpage Userpage) *UserpageRevision {
+	return &UserpageRevision{
+		ID:          primitive.NewObjectID(),
+		UserpageID:  userpage.ID,
+		Version:     userpage.Version,
+		UpdatedAt:   userpage.UpdatedAt,
+		UndoVersion: userpage.UndoVersion,
+		// Copied since edits may change the page's slice in place
+		Components: append([]Component{}, userpage.Components...),
+	}
+}
@@ -0,0 +1,154 @@
+// Package render renders public userpages as standalone HTML, for search
+// engines and readers without JavaScript, and their posts as RSS and Atom
+// feeds.
+package render
+
+import (
+	"bytes"
+	"embed"
+	"encoding/xml"
+	htmltemplate "html/template"
+	"io"
+	"net/url"
+	"sane-discourse-backend/internal/models"
+	"sort"
+	texttemplate "text/template"
+	"time"
+)
+
+// MaxFeedItems limits feeds to the most recently added posts.
+const MaxFeedItems = 50
+
+//go:embed templates
+var templateFS embed.FS
+
+var (
+	htmlTemplates = htmltemplate.Must(htmltemplate.New("").Funcs(htmltemplate.FuncMap{
+		"markdown": func(renderedHTML string) htmltemplate.HTML {
+			// Paragraph HTML is sanitized by utils.RenderMarkdown
+			return htmltemplate.HTML(renderedHTML)
+		},
+	}).ParseFS(templateFS, "templates/*.html.tmpl"))
+	feedTemplates = texttemplate.Must(texttemplate.New("").Funcs(texttemplate.FuncMap{
+		"xml": escapeXML,
+		"rfc822": func(t time.Time) string {
+			return t.UTC().Format(time.RFC1123Z)
+		},
+		"rfc3339": func(t time.Time) string {
+			return t.UTC().Format(time.RFC3339)
+		},
+	}).ParseFS(templateFS, "templates/*.xml.tmpl"))
+)
+
+// Page is a userpage to render. BaseURL is the absolute URL under which
+// the pages of its user are served, e.g. "https://example.com/pages/tim".
+// ShareToken is added to the links of unlisted pages.
+type Page struct {
+	Userpage   *models.PublicUserpage
+	BaseURL    string
+	ShareToken string
+}
+
+func (p Page) URL() string {
+	return p.pageURL("")
+}
+
+func (p Page) RSSURL() string {
+	return p.pageURL("/rss.xml")
+}
+
+func (p Page) AtomURL() string {
+	return p.pageURL("/atom.xml")
+}
+
+func (p Page) pageURL(suffix string) string {
+	pageURL := p.BaseURL + "/" + url.PathEscape(p.Userpage.Slug) + suffix
+	if p.ShareToken != "" {
+		pageURL += "?token=" + url.QueryEscape(p.ShareToken)
+	}
+	return pageURL
+}
+
+// FeedItem is one post of a page's feed. Published is when the post was
+// added to the page, taken from the component ID.
+type FeedItem struct {
+	ID        string
+	Post      *models.Post
+	Published time.Time
+}
+
+type feedData struct {
+	Page
+	Items   []FeedItem
+	Updated time.Time
+}
+
+// HTML writes the page as a standalone HTML document. Its components must
+// be resolved.
+func HTML(w io.Writer, page Page) error {
+	return execute(w, htmlTemplates, "userpage.html.tmpl", page)
+}
+
+// RSS writes the posts of the page as an RSS 2.0 feed.
+func RSS(w io.Writer, page Page) error {
+	return execute(w, feedTemplates, "rss.xml.tmpl", newFeedData(page))
+}
+
+// Atom writes the posts of the page as an Atom feed.
+func Atom(w io.Writer, page Page) error {
+	return execute(w, feedTemplates, "atom.xml.tmpl", newFeedData(page))
+}
+
+type template interface {
+	ExecuteTemplate(w io.Writer, name string, data any) error
+}
+
+// execute renders into a buffer first, so nothing is written if rendering
+// fails halfway.
+func execute(w io.Writer, t template, name string, data any) error {
+	var buf bytes.Buffer
+	if err := t.ExecuteTemplate(&buf, name, data); err != nil {
+		return err
+	}
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// FeedItems returns the resolved posts of the page's post components,
+// newest first. Posts inside embedded collections are not included.
+func FeedItems(userpage *models.PublicUserpage) []FeedItem {
+	items := []FeedItem{}
+	for _, component := range userpage.Components {
+		if component.Post == nil || component.Post.Post == nil {
+			continue
+		}
+		items = append(items, FeedItem{
+			ID:        component.ID.Hex(),
+			Post:      component.Post.Post,
+			Published: component.ID.Timestamp(),
+		})
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].Published.After(items[j].Published)
+	})
+	if len(items) > MaxFeedItems {
+		items = items[:MaxFeedItems]
+	}
+	return items
+}
+
+func newFeedData(page Page) feedData {
+	items := FeedItems(page.Userpage)
+	updated := page.Userpage.UpdatedAt
+	if len(items) > 0 && items[0].Published.After(updated) {
+		updated = items[0].Published
+	}
+	return feedData{Page: page, Items: items, Updated: updated}
+}
+
+func escapeXML(s string) string {
+	var buf bytes.Buffer
+	// Writing to a bytes.Buffer can't fail
+	_ = xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
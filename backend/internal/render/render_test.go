@@ -0,0 +1,108 @@
+package render
+
+import (
+	"bytes"
+	"encoding/xml"
+	"sane-discourse-backend/internal/models"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func testPage() Page {
+	older := primitive.NewObjectIDFromTimestamp(time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC))
+	newer := primitive.NewObjectIDFromTimestamp(time.Date(2025, 2, 1, 12, 0, 0, 0, time.UTC))
+	return Page{
+		BaseURL: "https://example.com/pages/tim",
+		Userpage: &models.PublicUserpage{
+			User:       models.UserProfile{ID: primitive.NewObjectID(), Username: "tim"},
+			Slug:       "reading",
+			Title:      "Reading <list>",
+			Visibility: models.UserpageVisibilityPublic,
+			Components: []models.Component{
+				{ID: primitive.NewObjectID(), Header: &models.HeaderComponent{Content: "Essays", Size: models.HeaderComponentSizeLarge}},
+				{ID: primitive.NewObjectID(), Paragraph: &models.PragraphComponent{Content: "*hi*", ContentHTML: "<p><em>hi</em></p>"}},
+				{ID: older, Post: &models.PostComponent{Size: models.PostComponentSizeMedium, Post: &models.Post{
+					Title: "On Caring", URL: "https://example.com/on-caring", Description: "Scope & scale",
+				}}},
+				{ID: newer, Post: &models.PostComponent{Size: models.PostComponentSizeSmall, Post: &models.Post{
+					Title: "Newer", URL: "https://example.com/newer",
+				}}},
+				{ID: primitive.NewObjectID(), Post: &models.PostComponent{Missing: true}},
+				{ID: primitive.NewObjectID(), Quote: &models.QuoteComponent{Content: "<script>alert(1)</script>"}},
+			},
+		},
+	}
+}
+
+func TestHTML(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, HTML(&buf, testPage()))
+	html := buf.String()
+	assert.Contains(t, html, "<title>Reading &lt;list&gt; · tim</title>")
+	assert.Contains(t, html, `<link rel="canonical" href="https://example.com/pages/tim/reading">`)
+	assert.Contains(t, html, "<h2>Essays</h2>")
+	assert.Contains(t, html, "<p><em>hi</em></p>")
+	assert.Contains(t, html, `<a href="https://example.com/on-caring" rel="nofollow noopener">On Caring</a>`)
+	assert.Contains(t, html, "This post has been deleted.")
+	assert.Contains(t, html, "&lt;script&gt;alert(1)&lt;/script&gt;")
+	assert.NotContains(t, html, "<script>")
+}
+
+func TestHTMLUnlisted(t *testing.T) {
+	page := testPage()
+	page.Userpage.Visibility = models.UserpageVisibilityUnlisted
+	page.ShareToken = "secret"
+	var buf bytes.Buffer
+	assert.NoError(t, HTML(&buf, page))
+	assert.Contains(t, buf.String(), `<meta name="robots" content="noindex, nofollow">`)
+	assert.NotContains(t, buf.String(), `rel="canonical"`)
+	assert.Contains(t, buf.String(), "https://example.com/pages/tim/reading/rss.xml?token=secret")
+}
+
+func TestRSS(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, RSS(&buf, testPage()))
+	var feed struct {
+		Channel struct {
+			Title string `xml:"title"`
+			Items []struct {
+				Title       string `xml:"title"`
+				Link        string `xml:"link"`
+				PubDate     string `xml:"pubDate"`
+				Description string `xml:"description"`
+			} `xml:"item"`
+		} `xml:"channel"`
+	}
+	assert.NoError(t, xml.Unmarshal(buf.Bytes(), &feed))
+	assert.Equal(t, "Reading <list> · tim", feed.Channel.Title)
+	assert.Equal(t, 2, len(feed.Channel.Items))
+	assert.Equal(t, "Newer", feed.Channel.Items[0].Title)
+	assert.Equal(t, "On Caring", feed.Channel.Items[1].Title)
+	assert.Equal(t, "Scope & scale", feed.Channel.Items[1].Description)
+	assert.Equal(t, "Wed, 01 Jan 2025 12:00:00 +0000", feed.Channel.Items[1].PubDate)
+}
+
+func TestAtom(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, Atom(&buf, testPage()))
+	var feed struct {
+		ID      string `xml:"id"`
+		Updated string `xml:"updated"`
+		Entries []struct {
+			Title     string `xml:"title"`
+			Published string `xml:"published"`
+			Link      struct {
+				Href string `xml:"href,attr"`
+			} `xml:"link"`
+		} `xml:"entry"`
+	}
+	assert.NoError(t, xml.Unmarshal(buf.Bytes(), &feed))
+	assert.Equal(t, "https://example.com/pages/tim/reading", feed.ID)
+	assert.Equal(t, "2025-02-01T12:00:00Z", feed.Updated)
+	assert.Equal(t, 2, len(feed.Entries))
+	assert.Equal(t, "https://example.com/newer", feed.Entries[0].Link.Href)
+	assert.Equal(t, "2025-01-01T12:00:00Z", feed.Entries[1].Published)
+}
@@ -0,0 +1,114 @@
+package repositories
+
+import (
+	"context"
+	"sane-discourse-backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type AddedPostRepository struct {
+	client *mongo.Client
+}
+
+func NewAddedPostRepository(client *mongo.Client) *AddedPostRepository {
+	return &AddedPostRepository{
+		client: client,
+	}
+}
+
+func (r *AddedPostRepository) collection() *mongo.Collection {
+	return r.client.Database("sane_discourse").Collection("added_posts")
+}
+
+func (r *AddedPostRepository) EnsureIndexes() error {
+	_, err := r.collection().Indexes().CreateMany(context.TODO(), []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "post_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "_id", Value: -1}},
+		},
+	})
+	return err
+}
+
+// Create is idempotent: adding a post twice keeps the first record.
+func (r *AddedPostRepository) Create(addedPost models.AddedPost) (*models.AddedPost, error) {
+	filter := bson.M{
+		"user_id": addedPost.UserID,
+		"post_id": addedPost.PostID,
+	}
+	addedPost.ID = primitive.NewObjectID()
+	err := r.collection().FindOneAndUpdate(
+		context.TODO(),
+		filter,
+		bson.M{"$setOnInsert": bson.M{"_id": addedPost.ID}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&addedPost)
+	if err != nil {
+		return nil, err
+	}
+	return &addedPost, nil
+}
+
+// FindRecentPostIDsByUserIDs returns the IDs of up to limit posts any of
+// the users added, most recently added first.
+func (r *AddedPostRepository) FindRecentPostIDsByUserIDs(userIDs []primitive.ObjectID, limit int) ([]primitive.ObjectID, error) {
+	cursor, err := r.collection().Find(
+		context.TODO(),
+		bson.M{"user_id": bson.M{"$in": userIDs}},
+		options.Find().
+			SetSort(bson.D{{Key: "_id", Value: -1}}).
+			SetLimit(int64(limit)).
+			SetProjection(bson.M{"post_id": 1}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.TODO())
+
+	var addedPosts []models.AddedPost
+	if err = cursor.All(context.TODO(), &addedPosts); err != nil {
+		return nil, err
+	}
+	postIDs := make([]primitive.ObjectID, len(addedPosts))
+	for i, addedPost := range addedPosts {
+		postIDs[i] = addedPost.PostID
+	}
+	return postIDs, nil
+}
+
+// MovePost moves the records of one post to another, as when duplicate
+// posts are merged. It returns the number of records moved.
+func (r *AddedPostRepository) MovePost(fromPostID, toPostID primitive.ObjectID) (int, error) {
+	cursor, err := r.collection().Find(context.TODO(), bson.M{"post_id": fromPostID})
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(context.TODO())
+
+	var addedPosts []models.AddedPost
+	if err = cursor.All(context.TODO(), &addedPosts); err != nil {
+		return 0, err
+	}
+	for _, addedPost := range addedPosts {
+		_, err = r.collection().UpdateOne(
+			context.TODO(),
+			bson.M{"_id": addedPost.ID},
+			bson.M{"$set": bson.M{"post_id": toPostID}},
+		)
+		if mongo.IsDuplicateKeyError(err) {
+			// The user also added the post it is merged into
+			_, err = r.collection().DeleteOne(context.TODO(), bson.M{"_id": addedPost.ID})
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(addedPosts), nil
+}
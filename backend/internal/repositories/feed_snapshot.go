@@ -0,0 +1,61 @@
+package repositories
+
+import (
+	"context"
+	"sane-discourse-backend/internal/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type FeedSnapshotRepository struct {
+	client *mongo.Client
+}
+
+func NewFeedSnapshotRepository(client *mongo.Client) *FeedSnapshotRepository {
+	return &FeedSnapshotRepository{
+		client: client,
+	}
+}
+
+func (r *FeedSnapshotRepository) collection() *mongo.Collection {
+	return r.client.Database("sane_discourse").Collection("feed_snapshots")
+}
+
+// EnsureIndexes lets MongoDB delete snapshots once they are older than
+// models.FeedSnapshotTTL.
+func (r *FeedSnapshotRepository) EnsureIndexes() error {
+	_, err := r.collection().Indexes().CreateOne(context.TODO(), mongo.IndexModel{
+		Keys:    bson.D{{Key: "created_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(int32(models.FeedSnapshotTTL.Seconds())),
+	})
+	return err
+}
+
+func (r *FeedSnapshotRepository) Create(snapshot models.FeedSnapshot) (*models.FeedSnapshot, error) {
+	_, err := r.collection().InsertOne(context.TODO(), snapshot)
+	if err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+// FindPostIDs returns up to limit post IDs of the snapshot, starting at
+// offset, without loading the rest. Expired snapshots are not returned even
+// if MongoDB hasn't deleted them yet.
+func (r *FeedSnapshotRepository) FindPostIDs(id primitive.ObjectID, offset, limit int) ([]primitive.ObjectID, error) {
+	filter := bson.M{
+		"_id":        id,
+		"created_at": bson.M{"$gt": time.Now().Add(-models.FeedSnapshotTTL)},
+	}
+	projection := bson.M{"post_ids": bson.M{"$slice": bson.A{offset, limit}}}
+	var snapshot models.FeedSnapshot
+	err := r.collection().FindOne(context.TODO(), filter, options.FindOne().SetProjection(projection)).Decode(&snapshot)
+	if err != nil {
+		return nil, err
+	}
+	return snapshot.PostIDs, nil
+}
@@ -0,0 +1,93 @@
+package repositories
+
+import (
+	"context"
+	"sane-discourse-backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type FollowRepository struct {
+	client *mongo.Client
+}
+
+func NewFollowRepository(client *mongo.Client) *FollowRepository {
+	return &FollowRepository{
+		client: client,
+	}
+}
+
+func (r *FollowRepository) collection() *mongo.Collection {
+	return r.client.Database("sane_discourse").Collection("follows")
+}
+
+func (r *FollowRepository) EnsureIndexes() error {
+	_, err := r.collection().Indexes().CreateMany(context.TODO(), []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "follower_id", Value: 1}, {Key: "followee_id", Value: 1}},
+			Options: options.Index().SetUnique(true),
+		},
+		{
+			Keys: bson.D{{Key: "followee_id", Value: 1}},
+		},
+	})
+	return err
+}
+
+// Create is idempotent: following someone twice keeps the first follow.
+func (r *FollowRepository) Create(follow models.Follow) (*models.Follow, error) {
+	filter := bson.M{
+		"follower_id": follow.FollowerID,
+		"followee_id": follow.FolloweeID,
+	}
+	follow.ID = primitive.NewObjectID()
+	err := r.collection().FindOneAndUpdate(
+		context.TODO(),
+		filter,
+		bson.M{"$setOnInsert": bson.M{"_id": follow.ID}},
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&follow)
+	if err != nil {
+		return nil, err
+	}
+	return &follow, nil
+}
+
+func (r *FollowRepository) FindByFollowerID(followerID primitive.ObjectID) ([]models.Follow, error) {
+	cursor, err := r.collection().Find(context.TODO(), bson.M{"follower_id": followerID})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.TODO())
+
+	var follows []models.Follow
+	if err = cursor.All(context.TODO(), &follows); err != nil {
+		return nil, err
+	}
+	return follows, nil
+}
+
+func (r *FollowRepository) FindByFolloweeID(followeeID primitive.ObjectID) ([]models.Follow, error) {
+	cursor, err := r.collection().Find(context.TODO(), bson.M{"followee_id": followeeID})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.TODO())
+
+	var follows []models.Follow
+	if err = cursor.All(context.TODO(), &follows); err != nil {
+		return nil, err
+	}
+	return follows, nil
+}
+
+func (r *FollowRepository) Delete(followerID, followeeID primitive.ObjectID) error {
+	_, err := r.collection().DeleteOne(context.TODO(), bson.M{
+		"follower_id": followerID,
+		"followee_id": followeeID,
+	})
+	return err
+}
@@ -0,0 +1,61 @@
+package repositories
+
+import (
+	"context"
+	"sane-discourse-backend/internal/models"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type LinkPreviewRepository struct {
+	client *mongo.Client
+}
+
+func NewLinkPreviewRepository(client *mongo.Client) *LinkPreviewRepository {
+	return &LinkPreviewRepository{
+		client: client,
+	}
+}
+
+func (r *LinkPreviewRepository) collection() *mongo.Collection {
+	return r.client.Database("sane_discourse").Collection("link_previews")
+}
+
+// EnsureIndexes lets MongoDB delete previews once they are older than
+// models.LinkPreviewTTL.
+func (r *LinkPreviewRepository) EnsureIndexes() error {
+	_, err := r.collection().Indexes().CreateOne(context.TODO(), mongo.IndexModel{
+		Keys:    bson.D{{Key: "fetched_at", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(int32(models.LinkPreviewTTL.Seconds())),
+	})
+	return err
+}
+
+// FindByCanonicalURL returns the preview of a link. Expired previews are
+// not returned even if MongoDB hasn't deleted them yet.
+func (r *LinkPreviewRepository) FindByCanonicalURL(canonicalURL string) (*models.LinkPreview, error) {
+	filter := bson.M{
+		"_id":        canonicalURL,
+		"fetched_at": bson.M{"$gt": time.Now().Add(-models.LinkPreviewTTL)},
+	}
+	var preview models.LinkPreview
+	err := r.collection().FindOne(context.TODO(), filter).Decode(&preview)
+	if err != nil {
+		return nil, err
+	}
+	return &preview, nil
+}
+
+// Save stores the preview, replacing an older one for the same link.
+func (r *LinkPreviewRepository) Save(preview models.LinkPreview) error {
+	_, err := r.collection().ReplaceOne(
+		context.TODO(),
+		bson.M{"_id": preview.CanonicalURL},
+		preview,
+		options.Replace().SetUpsert(true),
+	)
+	return err
+}
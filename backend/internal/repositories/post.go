@@ -7,6 +7,7 @@ import (
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type PostRepository struct {
@@ -23,8 +24,34 @@ func (r *PostRepository) collection() *mongo.Collection {
 	return r.client.Database("sane_discourse").Collection("posts")
 }
 
+// EnsureIndexes backs the feed rankings that sort by stored fields, see
+// RankQuery.
+func (r *PostRepository) EnsureIndexes() error {
+	_, err := r.collection().Indexes().CreateMany(context.TODO(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "reactions.total", Value: -1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "reactions.scores.importance", Value: -1}, {Key: "_id", Value: -1}}},
+	})
+	return err
+}
+
+// EnsureCanonicalURLIndex allows a single post per canonical URL. Posts
+// created before canonical URLs were stored don't have one and are not
+// indexed.
+func (r *PostRepository) EnsureCanonicalURLIndex() error {
+	_, err := r.collection().Indexes().CreateOne(context.TODO(), mongo.IndexModel{
+		Keys: bson.D{{Key: "canonical_url", Value: 1}},
+		Options: options.Index().
+			SetUnique(true).
+			SetPartialFilterExpression(bson.M{"canonical_url": bson.M{"$type": "string"}}),
+	})
+	return err
+}
+
 func (r *PostRepository) Create(post models.Post) (*models.Post, error) {
 	post.ID = primitive.NewObjectID()
+	// Counters are only ever changed by ReactionRepository.
+	post.Reactions = models.NewReactionSummary()
+	post.MyReaction = nil
 	result, err := r.collection().InsertOne(context.TODO(), post)
 	if err != nil {
 		return nil, err
@@ -51,6 +78,15 @@ func (r *PostRepository) FindByURL(url string) (*models.Post, error) {
 	return &post, nil
 }
 
+func (r *PostRepository) FindByCanonicalURL(canonicalURL string) (*models.Post, error) {
+	var post models.Post
+	err := r.collection().FindOne(context.TODO(), bson.M{"canonical_url": canonicalURL}).Decode(&post)
+	if err != nil {
+		return nil, err
+	}
+	return &post, nil
+}
+
 func (r *PostRepository) FindAll() ([]models.Post, error) {
 	cursor, err := r.collection().Find(context.TODO(), bson.M{})
 	if err != nil {
@@ -79,23 +115,64 @@ func (r *PostRepository) Delete(id primitive.ObjectID) error {
 	return err
 }
 
-func (r *PostRepository) FindPostsReactedByUser(userID primitive.ObjectID) ([]models.Post, error) {
-	pipeline := []bson.M{
-		{"$lookup": bson.M{
-			"from":         "reactions",
-			"localField":   "_id",
-			"foreignField": "post_id",
-			"as":           "reactions",
-		}},
-		{"$match": bson.M{
-			"reactions.user_id": userID,
-		}},
-		{"$project": bson.M{
-			"reactions": 0,
-		}},
+// FindPostsReactedByUser returns up to limit posts the user reacted to, most
+// recent reaction first. A non-zero afterReactionID continues after that
+// reaction. If there are more posts, the returned ID is the reaction to
+// continue after, otherwise it is zero.
+func (r *PostRepository) FindPostsReactedByUser(userID primitive.ObjectID, afterReactionID primitive.ObjectID, limit int) ([]models.Post, primitive.ObjectID, error) {
+	filter := bson.M{"user_id": userID}
+	if !afterReactionID.IsZero() {
+		filter["_id"] = bson.M{"$lt": afterReactionID}
+	}
+	reactions := r.client.Database("sane_discourse").Collection("reactions")
+	reactionCursor, err := reactions.Find(
+		context.TODO(),
+		filter,
+		options.Find().
+			SetSort(bson.D{{Key: "_id", Value: -1}}).
+			SetLimit(int64(limit+1)).
+			SetProjection(bson.M{"post_id": 1}),
+	)
+	if err != nil {
+		return nil, primitive.NilObjectID, err
 	}
+	defer reactionCursor.Close(context.TODO())
 
-	cursor, err := r.collection().Aggregate(context.TODO(), pipeline)
+	var userReactions []models.Reaction
+	if err = reactionCursor.All(context.TODO(), &userReactions); err != nil {
+		return nil, primitive.NilObjectID, err
+	}
+	if len(userReactions) == 0 {
+		return []models.Post{}, primitive.NilObjectID, nil
+	}
+	nextReactionID := primitive.NilObjectID
+	if len(userReactions) > limit {
+		userReactions = userReactions[:limit]
+		nextReactionID = userReactions[limit-1].ID
+	}
+
+	postIDs := make([]primitive.ObjectID, len(userReactions))
+	for i, reaction := range userReactions {
+		postIDs[i] = reaction.PostID
+	}
+	postsByID, err := r.FindByIDs(postIDs)
+	if err != nil {
+		return nil, primitive.NilObjectID, err
+	}
+
+	// Keep the reaction order and skip reactions on deleted posts.
+	posts := []models.Post{}
+	for _, reaction := range userReactions {
+		if post, ok := postsByID[reaction.PostID]; ok {
+			posts = append(posts, post)
+		}
+	}
+	return posts, nextReactionID, nil
+}
+
+// FindByIDs loads many posts in one query. Missing posts are absent from the map.
+func (r *PostRepository) FindByIDs(ids []primitive.ObjectID) (map[primitive.ObjectID]models.Post, error) {
+	cursor, err := r.collection().Find(context.TODO(), bson.M{"_id": bson.M{"$in": ids}})
 	if err != nil {
 		return nil, err
 	}
@@ -105,28 +182,62 @@ func (r *PostRepository) FindPostsReactedByUser(userID primitive.ObjectID) ([]mo
 	if err = cursor.All(context.TODO(), &posts); err != nil {
 		return nil, err
 	}
+	postsByID := make(map[primitive.ObjectID]models.Post, len(posts))
+	for _, post := range posts {
+		postsByID[post.ID] = post
+	}
+	return postsByID, nil
+}
 
-	return posts, nil
+// MaxRankCandidates caps how many posts a RankQuery with a Score computes
+// the score for.
+const MaxRankCandidates = 1000
+
+// RankQuery describes how FindRankedIDs orders posts.
+type RankQuery struct {
+	// Sort orders the posts, highest first. It must be backed by an index
+	// and end with _id, see EnsureIndexes.
+	Sort bson.D
+	// Candidates narrows down the posts that are ranked.
+	Candidates bson.M
+	// Score, if set, ranks the first MaxRankCandidates candidates in Sort
+	// order by this expression instead.
+	Score bson.M
 }
 
-func (r *PostRepository) FindAllSortedByReactionCount() ([]models.Post, error) {
+// IsStable reports whether the order of the posts never changes, so that
+// a listing can be continued after its last post.
+func (q RankQuery) IsStable() bool {
+	return q.Score == nil && len(q.Sort) == 1 && q.Sort[0].Key == "_id"
+}
+
+// FindRankedIDs returns the IDs of up to limit posts matching filter in the
+// order of query.
+func (r *PostRepository) FindRankedIDs(filter bson.M, query RankQuery, limit int) ([]primitive.ObjectID, error) {
+	match := bson.A{filter}
+	if query.Candidates != nil {
+		match = append(match, query.Candidates)
+	}
 	pipeline := []bson.M{
-		{"$lookup": bson.M{
-			"from":         "reactions",
-			"localField":   "_id",
-			"foreignField": "post_id",
-			"as":           "reactions",
-		}},
-		{"$addFields": bson.M{
-			"reaction_count": bson.M{"$size": "$reactions"},
-		}},
-		{"$sort": bson.M{
-			"reaction_count": -1,
-		}},
-		{"$project": bson.M{
-			"reactions": 0,
-		}},
+		{"$match": bson.M{"$and": match}},
+		{"$sort": query.Sort},
 	}
+	if query.Score == nil {
+		pipeline = append(pipeline, bson.M{"$limit": limit})
+	} else {
+		pipeline = append(pipeline,
+			bson.M{"$limit": MaxRankCandidates},
+			bson.M{"$addFields": bson.M{
+				"rank_score": bson.M{"$toDouble": query.Score},
+			}},
+			bson.M{"$sort": bson.D{
+				{Key: "rank_score", Value: -1},
+				{Key: "_id", Value: -1},
+			}},
+			bson.M{"$limit": limit},
+		)
+	}
+	pipeline = append(pipeline, bson.M{"$project": bson.M{"_id": 1}})
 
 	cursor, err := r.collection().Aggregate(context.TODO(), pipeline)
 	if err != nil {
@@ -134,10 +245,15 @@ func (r *PostRepository) FindAllSortedByReactionCount() ([]models.Post, error) {
 	}
 	defer cursor.Close(context.TODO())
 
-	var posts []models.Post
-	if err = cursor.All(context.TODO(), &posts); err != nil {
+	var rankedPosts []struct {
+		ID primitive.ObjectID `bson:"_id"`
+	}
+	if err = cursor.All(context.TODO(), &rankedPosts); err != nil {
 		return nil, err
 	}
-
-	return posts, nil
+	postIDs := make([]primitive.ObjectID, len(rankedPosts))
+	for i, rankedPost := range rankedPosts {
+		postIDs[i] = rankedPost.ID
+	}
+	return postIDs, nil
 }
@@ -3,10 +3,12 @@ package repositories
 import (
 	"context"
 	"sane-discourse-backend/internal/models"
+	"sane-discourse-backend/pkg/types"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 type ReactionRepository struct {
@@ -23,13 +25,53 @@ func (r *ReactionRepository) collection() *mongo.Collection {
 	return r.client.Database("sane_discourse").Collection("reactions")
 }
 
+// EnsureIndexes enforces a single reaction document per user and post, and
+// indexes the most recent reactions of each user and post. It fails while
+// legacy one-row-per-type reactions are still present.
+func (r *ReactionRepository) EnsureIndexes() error {
+	_, err := r.collection().Indexes().CreateMany(context.TODO(), []mongo.IndexModel{
+		{Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "_id", Value: -1}}},
+		{Keys: bson.D{{Key: "post_id", Value: 1}, {Key: "_id", Value: -1}}},
+	})
+	if err != nil {
+		return err
+	}
+	_, err = r.collection().Indexes().CreateOne(context.TODO(), mongo.IndexModel{
+		Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "post_id", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
+// Create inserts the reaction and bumps the post's reaction counters in the
+// same transaction. Update and Delete keep the counters in sync the same way.
 func (r *ReactionRepository) Create(reaction models.Reaction) (*models.Reaction, error) {
 	reaction.ID = primitive.NewObjectID()
-	result, err := r.collection().InsertOne(context.TODO(), reaction)
+	err := r.withTransaction(func(ctx mongo.SessionContext) error {
+		_, err := r.collection().InsertOne(ctx, reaction)
+		if err != nil {
+			return err
+		}
+		return r.updateCounters(ctx, reaction.PostID, nil, &reaction)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &reaction, nil
+}
+
+func (r *ReactionRepository) Update(reaction models.Reaction) (*models.Reaction, error) {
+	err := r.withTransaction(func(ctx mongo.SessionContext) error {
+		var before models.Reaction
+		err := r.collection().FindOneAndReplace(ctx, bson.M{"_id": reaction.ID}, reaction).Decode(&before)
+		if err != nil {
+			return err
+		}
+		return r.updateCounters(ctx, reaction.PostID, &before, &reaction)
+	})
 	if err != nil {
 		return nil, err
 	}
-	reaction.ID = result.InsertedID.(primitive.ObjectID)
 	return &reaction, nil
 }
 
@@ -56,6 +98,82 @@ func (r *ReactionRepository) FindByUserID(userID primitive.ObjectID) ([]models.R
 	return reactions, nil
 }
 
+// FindRecentByUserID returns up to limit of the user's reactions, newest first.
+func (r *ReactionRepository) FindRecentByUserID(userID primitive.ObjectID, limit int) ([]models.Reaction, error) {
+	cursor, err := r.collection().Find(
+		context.TODO(),
+		bson.M{"user_id": userID},
+		options.Find().SetSort(bson.D{{Key: "_id", Value: -1}}).SetLimit(int64(limit)),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.TODO())
+
+	var reactions []models.Reaction
+	if err = cursor.All(context.TODO(), &reactions); err != nil {
+		return nil, err
+	}
+	return reactions, nil
+}
+
+// FindRecentByUserIDs returns up to limit of each user's reactions, newest
+// first per user, in a single aggregation.
+func (r *ReactionRepository) FindRecentByUserIDs(userIDs []primitive.ObjectID, limit int) ([]models.Reaction, error) {
+	pipeline := []bson.M{
+		{"$match": bson.M{"user_id": bson.M{"$in": userIDs}}},
+		{"$group": bson.M{
+			"_id": "$user_id",
+			"reactions": bson.M{"$topN": bson.M{
+				"n":      limit,
+				"sortBy": bson.M{"_id": -1},
+				"output": "$$ROOT",
+			}},
+		}},
+		{"$unwind": "$reactions"},
+		{"$replaceRoot": bson.M{"newRoot": "$reactions"}},
+	}
+	cursor, err := r.collection().Aggregate(context.TODO(), pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.TODO())
+
+	reactions := []models.Reaction{}
+	if err = cursor.All(context.TODO(), &reactions); err != nil {
+		return nil, err
+	}
+	return reactions, nil
+}
+
+// FindRecentPostIDsByUserIDs returns the IDs of the posts of up to limit
+// reactions of any of the users, most recent reaction first. A post can
+// appear more than once.
+func (r *ReactionRepository) FindRecentPostIDsByUserIDs(userIDs []primitive.ObjectID, limit int) ([]primitive.ObjectID, error) {
+	cursor, err := r.collection().Find(
+		context.TODO(),
+		bson.M{"user_id": bson.M{"$in": userIDs}},
+		options.Find().
+			SetSort(bson.D{{Key: "_id", Value: -1}}).
+			SetLimit(int64(limit)).
+			SetProjection(bson.M{"post_id": 1}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.TODO())
+
+	var reactions []models.Reaction
+	if err = cursor.All(context.TODO(), &reactions); err != nil {
+		return nil, err
+	}
+	postIDs := make([]primitive.ObjectID, len(reactions))
+	for i, reaction := range reactions {
+		postIDs[i] = reaction.PostID
+	}
+	return postIDs, nil
+}
+
 func (r *ReactionRepository) FindByPostID(postID primitive.ObjectID) ([]models.Reaction, error) {
 	cursor, err := r.collection().Find(context.TODO(), bson.M{"post_id": postID})
 	if err != nil {
@@ -70,11 +188,62 @@ func (r *ReactionRepository) FindByPostID(postID primitive.ObjectID) ([]models.R
 	return reactions, nil
 }
 
-func (r *ReactionRepository) FindByUserIDAndPostID(userID, postID primitive.ObjectID) ([]models.Reaction, error) {
+func (r *ReactionRepository) FindByUserIDAndPostID(userID, postID primitive.ObjectID) (*models.Reaction, error) {
 	filter := bson.M{
 		"user_id": userID,
 		"post_id": postID,
 	}
+	var reaction models.Reaction
+	err := r.collection().FindOne(context.TODO(), filter).Decode(&reaction)
+	if err != nil {
+		return nil, err
+	}
+	return &reaction, nil
+}
+
+func (r *ReactionRepository) FindByPostIDs(postIDs []primitive.ObjectID) ([]models.Reaction, error) {
+	cursor, err := r.collection().Find(context.TODO(), bson.M{"post_id": bson.M{"$in": postIDs}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.TODO())
+
+	var reactions []models.Reaction
+	if err = cursor.All(context.TODO(), &reactions); err != nil {
+		return nil, err
+	}
+	return reactions, nil
+}
+
+// FindRecentByPostIDs returns up to limit of the reactions on the given
+// posts, newest first. Reactions of excludedUserID are left out.
+func (r *ReactionRepository) FindRecentByPostIDs(postIDs []primitive.ObjectID, excludedUserID primitive.ObjectID, limit int) ([]models.Reaction, error) {
+	filter := bson.M{
+		"post_id": bson.M{"$in": postIDs},
+		"user_id": bson.M{"$ne": excludedUserID},
+	}
+	cursor, err := r.collection().Find(
+		context.TODO(),
+		filter,
+		options.Find().SetSort(bson.D{{Key: "_id", Value: -1}}).SetLimit(int64(limit)),
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.TODO())
+
+	var reactions []models.Reaction
+	if err = cursor.All(context.TODO(), &reactions); err != nil {
+		return nil, err
+	}
+	return reactions, nil
+}
+
+func (r *ReactionRepository) FindByUserIDAndPostIDs(userID primitive.ObjectID, postIDs []primitive.ObjectID) ([]models.Reaction, error) {
+	filter := bson.M{
+		"user_id": userID,
+		"post_id": bson.M{"$in": postIDs},
+	}
 	cursor, err := r.collection().Find(context.TODO(), filter)
 	if err != nil {
 		return nil, err
@@ -103,9 +272,261 @@ func (r *ReactionRepository) FindAll() ([]models.Reaction, error) {
 }
 
 func (r *ReactionRepository) Delete(userID, postID primitive.ObjectID) error {
-	_, err := r.collection().DeleteOne(context.TODO(), bson.M{
-		"user_id": userID,
-		"post_id": postID,
+	return r.withTransaction(func(ctx mongo.SessionContext) error {
+		var before models.Reaction
+		err := r.collection().FindOneAndDelete(ctx, bson.M{
+			"user_id": userID,
+			"post_id": postID,
+		}).Decode(&before)
+		if err == mongo.ErrNoDocuments {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return r.updateCounters(ctx, postID, &before, nil)
+	})
+}
+
+func (r *ReactionRepository) postCollection() *mongo.Collection {
+	return r.client.Database("sane_discourse").Collection("posts")
+}
+
+func (r *ReactionRepository) withTransaction(fn func(ctx mongo.SessionContext) error) error {
+	session, err := r.client.StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(context.TODO())
+	_, err = session.WithTransaction(context.TODO(), func(ctx mongo.SessionContext) (interface{}, error) {
+		return nil, fn(ctx)
 	})
 	return err
 }
+
+// updateCounters moves the post's reaction counters from before to after.
+// Either side may be nil for a created or deleted reaction.
+func (r *ReactionRepository) updateCounters(ctx context.Context, postID primitive.ObjectID, before, after *models.Reaction) error {
+	deltas := map[string]int{}
+	apply := func(reaction *models.Reaction, sign int) {
+		if reaction == nil {
+			return
+		}
+		deltas["reactions.total"] += sign
+		for _, reactionType := range reaction.ReactionTypes() {
+			deltas["reactions.counts."+string(reactionType)] += sign
+		}
+		for _, axis := range types.ReactionAxes {
+			deltas["reactions.scores."+string(axis)] += sign * int(reaction.Get(axis))
+		}
+	}
+	apply(before, -1)
+	apply(after, 1)
+
+	increments := bson.M{}
+	for field, delta := range deltas {
+		if delta != 0 {
+			increments[field] = delta
+		}
+	}
+	if len(increments) == 0 {
+		return nil
+	}
+	_, err := r.postCollection().UpdateOne(ctx, bson.M{"_id": postID}, bson.M{"$inc": increments})
+	return err
+}
+
+// RecomputeReactionCounts rebuilds the reaction counters of every post from
+// the raw reactions. Each post is read and updated in its own transaction,
+// so reactions changed meanwhile are neither lost nor counted twice, and
+// posts keep their counters until they are replaced. It returns the number
+// of posts that have reactions.
+func (r *ReactionRepository) RecomputeReactionCounts() (int, error) {
+	cursor, err := r.postCollection().Find(
+		context.TODO(),
+		bson.M{},
+		options.Find().SetProjection(bson.M{"_id": 1}),
+	)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(context.TODO())
+
+	posts := 0
+	for cursor.Next(context.TODO()) {
+		var post struct {
+			ID primitive.ObjectID `bson:"_id"`
+		}
+		if err := cursor.Decode(&post); err != nil {
+			return posts, err
+		}
+		reacted := false
+		err := r.withTransaction(func(ctx mongo.SessionContext) error {
+			reactionCursor, err := r.collection().Find(
+				ctx,
+				bson.M{"post_id": post.ID, "reaction_type": bson.M{"$exists": false}},
+			)
+			if err != nil {
+				return err
+			}
+			var reactions []models.Reaction
+			if err = reactionCursor.All(ctx, &reactions); err != nil {
+				return err
+			}
+			summary := models.NewReactionSummary()
+			for _, reaction := range reactions {
+				summary.Add(reaction)
+			}
+			reacted = len(reactions) > 0
+			_, err = r.postCollection().UpdateOne(
+				ctx,
+				bson.M{"_id": post.ID},
+				bson.M{"$set": bson.M{"reactions": summary}},
+			)
+			return err
+		})
+		if err != nil {
+			return posts, err
+		}
+		if reacted {
+			posts++
+		}
+	}
+	return posts, cursor.Err()
+}
+
+// legacyReaction is the pre-axis document shape: one row per reaction type.
+type legacyReaction struct {
+	ID           primitive.ObjectID `bson:"_id"`
+	ReactionType types.ReactionType `bson:"reaction_type"`
+	UserID       primitive.ObjectID `bson:"user_id"`
+	PostID       primitive.ObjectID `bson:"post_id"`
+}
+
+// MigrateLegacyReactions folds legacy reaction_type rows into one axis-based
+// document per user and post. When legacy rows contradict each other on an
+// axis, the most recently created row wins; values already stored in the new
+// shape win over legacy rows. It returns the number of user/post pairs that
+// were migrated.
+func (r *ReactionRepository) MigrateLegacyReactions() (int, error) {
+	cursor, err := r.collection().Find(
+		context.TODO(),
+		bson.M{"reaction_type": bson.M{"$exists": true}},
+		options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}),
+	)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(context.TODO())
+
+	var legacyReactions []legacyReaction
+	if err = cursor.All(context.TODO(), &legacyReactions); err != nil {
+		return 0, err
+	}
+
+	type pairKey struct {
+		userID primitive.ObjectID
+		postID primitive.ObjectID
+	}
+	pairs := []pairKey{}
+	legacyIDs := map[pairKey][]primitive.ObjectID{}
+	migrated := map[pairKey]*models.Reaction{}
+	for _, legacy := range legacyReactions {
+		key := pairKey{legacy.UserID, legacy.PostID}
+		reaction, ok := migrated[key]
+		if !ok {
+			reaction = &models.Reaction{
+				ID:     legacy.ID,
+				UserID: legacy.UserID,
+				PostID: legacy.PostID,
+			}
+			migrated[key] = reaction
+			pairs = append(pairs, key)
+		}
+		if legacy.ReactionType.IsValid() {
+			reaction.Set(legacy.ReactionType.Axis(), legacy.ReactionType.Value())
+		}
+		legacyIDs[key] = append(legacyIDs[key], legacy.ID)
+	}
+
+	for _, key := range pairs {
+		reaction := migrated[key]
+		var existing models.Reaction
+		err = r.collection().FindOne(context.TODO(), bson.M{
+			"user_id":       key.userID,
+			"post_id":       key.postID,
+			"reaction_type": bson.M{"$exists": false},
+		}).Decode(&existing)
+		if err != nil && err != mongo.ErrNoDocuments {
+			return 0, err
+		}
+		if err == nil {
+			reaction.ID = existing.ID
+			for _, axis := range types.ReactionAxes {
+				if value := existing.Get(axis); value != types.ReactionValueNone {
+					reaction.Set(axis, value)
+				}
+			}
+		}
+
+		_, err = r.collection().DeleteMany(context.TODO(), bson.M{"_id": bson.M{"$in": legacyIDs[key]}})
+		if err != nil {
+			return 0, err
+		}
+		if reaction.IsEmpty() {
+			continue
+		}
+		_, err = r.collection().ReplaceOne(
+			context.TODO(),
+			bson.M{"_id": reaction.ID},
+			reaction,
+			options.Replace().SetUpsert(true),
+		)
+		if err != nil {
+			return 0, err
+		}
+	}
+	return len(pairs), nil
+}
+
+// MovePostReactions moves all reactions on one post to another, as when
+// duplicate posts are merged. A user who reacted to both keeps their
+// reaction on the target, with the axes it leaves empty taken from the
+// moved one. Post counters are not updated, so RecomputeReactionCounts has
+// to run afterwards. It returns the number of reactions moved.
+func (r *ReactionRepository) MovePostReactions(fromPostID, toPostID primitive.ObjectID) (int, error) {
+	reactions, err := r.FindByPostID(fromPostID)
+	if err != nil {
+		return 0, err
+	}
+	for _, reaction := range reactions {
+		existing, err := r.FindByUserIDAndPostID(reaction.UserID, toPostID)
+		if err != nil && err != mongo.ErrNoDocuments {
+			return 0, err
+		}
+		if existing == nil {
+			_, err = r.collection().UpdateOne(
+				context.TODO(),
+				bson.M{"_id": reaction.ID},
+				bson.M{"$set": bson.M{"post_id": toPostID}},
+			)
+			if err != nil {
+				return 0, err
+			}
+			continue
+		}
+
+		for _, axis := range types.ReactionAxes {
+			if existing.Get(axis) == types.ReactionValueNone {
+				existing.Set(axis, reaction.Get(axis))
+			}
+		}
+		if _, err = r.collection().ReplaceOne(context.TODO(), bson.M{"_id": existing.ID}, existing); err != nil {
+			return 0, err
+		}
+		if _, err = r.collection().DeleteOne(context.TODO(), bson.M{"_id": reaction.ID}); err != nil {
+			return 0, err
+		}
+	}
+	return len(reactions), nil
+}
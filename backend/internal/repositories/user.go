@@ -42,6 +42,20 @@ func (r *UserRepository) FindByID(id primitive.ObjectID) (*models.User, error) {
 	return &user, nil
 }
 
+func (r *UserRepository) FindByIDs(ids []primitive.ObjectID) ([]models.User, error) {
+	cursor, err := r.collection().Find(context.TODO(), bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.TODO())
+
+	var users []models.User
+	if err = cursor.All(context.TODO(), &users); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
 func (r *UserRepository) FindByUsername(username string) (*models.User, error) {
 	var user models.User
 	err := r.collection().FindOne(context.TODO(), bson.M{"username": username}).Decode(&user)
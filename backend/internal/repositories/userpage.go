@@ -2,13 +2,17 @@ package repositories
 
 import (
 	"context"
+	"errors"
 	"sane-discourse-backend/internal/models"
 
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+var ErrVersionConflict = errors.New("userpage was modified concurrently")
+
 type UserpageRepository struct {
 	client *mongo.Client
 }
@@ -32,37 +36,71 @@ func (r *UserpageRepository) Create(userpage models.Userpage) (*models.Userpage,
 	return &userpage, nil
 }
 
+// EnsureIndexes makes slugs unique per user. Pages stored without a slug
+// index as null, of which every user has at most one.
+func (r *UserpageRepository) EnsureIndexes() error {
+	_, err := r.collection().Indexes().CreateOne(context.TODO(), mongo.IndexModel{
+		Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "slug", Value: 1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
 func (r *UserpageRepository) FindByID(id primitive.ObjectID) (*models.Userpage, error) {
-	var userpage models.Userpage
-	err := r.collection().FindOne(context.TODO(), bson.M{"_id": id}).Decode(&userpage)
+	return r.findOne(bson.M{"_id": id})
+}
+
+func (r *UserpageRepository) FindByUserIDAndSlug(userID primitive.ObjectID, slug string) (*models.Userpage, error) {
+	return r.findOne(bson.M{"user_id": userID, "slug": slugFilter(slug)})
+}
+
+// FindAllByUserID returns the user's pages in their order.
+func (r *UserpageRepository) FindAllByUserID(userID primitive.ObjectID) ([]models.Userpage, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "position", Value: 1}, {Key: "_id", Value: 1}})
+	return r.find(bson.M{"user_id": userID}, opts)
+}
+
+// FindByUserIDs returns the pages of all given users, grouped by user.
+func (r *UserpageRepository) FindByUserIDs(userIDs []primitive.ObjectID) (map[primitive.ObjectID][]models.Userpage, error) {
+	userpages, err := r.find(bson.M{"user_id": bson.M{"$in": userIDs}}, options.Find())
 	if err != nil {
 		return nil, err
 	}
-	return &userpage, nil
+	userpagesByUserID := make(map[primitive.ObjectID][]models.Userpage, len(userIDs))
+	for _, userpage := range userpages {
+		userpagesByUserID[userpage.UserID] = append(userpagesByUserID[userpage.UserID], userpage)
+	}
+	return userpagesByUserID, nil
 }
 
-func (r *UserpageRepository) FindByUserID(userID primitive.ObjectID) (*models.Userpage, error) {
+func (r *UserpageRepository) FindAll() ([]models.Userpage, error) {
+	return r.find(bson.M{}, options.Find())
+}
+
+func (r *UserpageRepository) findOne(filter bson.M) (*models.Userpage, error) {
 	var userpage models.Userpage
-	err := r.collection().FindOne(context.TODO(), bson.M{"user_id": userID}).Decode(&userpage)
+	err := r.collection().FindOne(context.TODO(), filter).Decode(&userpage)
 	if err != nil {
 		return nil, err
 	}
+	userpage.ApplyDefaults()
 	return &userpage, nil
 }
 
-func (r *UserpageRepository) FindAll() ([]models.Userpage, error) {
-	cursor, err := r.collection().Find(context.TODO(), bson.M{})
+func (r *UserpageRepository) find(filter bson.M, opts *options.FindOptions) ([]models.Userpage, error) {
+	cursor, err := r.collection().Find(context.TODO(), filter, opts)
 	if err != nil {
 		return nil, err
 	}
 	defer cursor.Close(context.TODO())
 
-	var userpages []models.Userpage
+	userpages := []models.Userpage{}
 	for cursor.Next(context.TODO()) {
 		var userpage models.Userpage
 		if err := cursor.Decode(&userpage); err != nil {
 			return nil, err
 		}
+		userpage.ApplyDefaults()
 		userpages = append(userpages, userpage)
 	}
 
@@ -73,6 +111,14 @@ func (r *UserpageRepository) FindAll() ([]models.Userpage, error) {
 	return userpages, nil
 }
 
+func slugFilter(slug string) any {
+	if slug == models.DefaultUserpageSlug {
+		// Pages stored before users could have several pages have no slug
+		return bson.M{"$in": bson.A{slug, nil}}
+	}
+	return slug
+}
+
 func (r *UserpageRepository) Update(userpage models.Userpage) (*models.Userpage, error) {
 	_, err := r.collection().ReplaceOne(context.TODO(), bson.M{"_id": userpage.ID}, userpage)
 	if err != nil {
@@ -81,7 +127,109 @@ func (r *UserpageRepository) Update(userpage models.Userpage) (*models.Userpage,
 	return &userpage, nil
 }
 
+// UpdateIfVersion replaces the userpage only if the stored version still
+// equals expectedVersion, and bumps the version. It returns
+// ErrVersionConflict if the page was changed in the meantime.
+func (r *UserpageRepository) UpdateIfVersion(userpage models.Userpage, expectedVersion int) (*models.Userpage, error) {
+	filter := versionFilter(userpage.ID, expectedVersion)
+	userpage.Version = expectedVersion + 1
+	result, err := r.collection().ReplaceOne(context.TODO(), filter, userpage)
+	if err != nil {
+		return nil, err
+	}
+	if result.MatchedCount == 0 {
+		return nil, ErrVersionConflict
+	}
+	return &userpage, nil
+}
+
+// SaveComponentIDs stores the component IDs assigned to a page without
+// bumping its version, since the content is unchanged. Nothing is written
+// if the page was edited in the meantime.
+func (r *UserpageRepository) SaveComponentIDs(userpage models.Userpage) error {
+	_, err := r.collection().UpdateOne(
+		context.TODO(),
+		versionFilter(userpage.ID, userpage.Version),
+		bson.M{"$set": bson.M{"components": userpage.Components}},
+	)
+	return err
+}
+
+// AssignComponentIDs gives every component stored without an ID a new one
+// and returns the number of pages changed.
+func (r *UserpageRepository) AssignComponentIDs() (int, error) {
+	userpages, err := r.FindAll()
+	if err != nil {
+		return 0, err
+	}
+	changed := 0
+	for _, userpage := range userpages {
+		if !userpage.EnsureComponentIDs() {
+			continue
+		}
+		if err := r.SaveComponentIDs(userpage); err != nil {
+			return changed, err
+		}
+		changed++
+	}
+	return changed, nil
+}
+
+func versionFilter(id primitive.ObjectID, version int) bson.M {
+	if version == 0 {
+		// Pages stored before versioning have no version field
+		return bson.M{"_id": id, "version": bson.M{"$in": bson.A{0, nil}}}
+	}
+	return bson.M{"_id": id, "version": version}
+}
+
+// UpdatePositions stores the order of the user's pages: the page
+// userpageIDs[i] gets position i.
+func (r *UserpageRepository) UpdatePositions(userID primitive.ObjectID, userpageIDs []primitive.ObjectID) error {
+	if len(userpageIDs) == 0 {
+		return nil
+	}
+	writes := make([]mongo.WriteModel, len(userpageIDs))
+	for i, id := range userpageIDs {
+		writes[i] = mongo.NewUpdateOneModel().
+			SetFilter(bson.M{"_id": id, "user_id": userID}).
+			SetUpdate(bson.M{"$set": bson.M{"position": i}})
+	}
+	_, err := r.collection().BulkWrite(context.TODO(), writes)
+	return err
+}
+
 func (r *UserpageRepository) Delete(id primitive.ObjectID) error {
 	_, err := r.collection().DeleteOne(context.TODO(), bson.M{"_id": id})
 	return err
 }
+
+// DeleteIfVersion deletes the userpage only if the stored version still
+// equals expectedVersion, and returns ErrVersionConflict otherwise.
+func (r *UserpageRepository) DeleteIfVersion(id primitive.ObjectID, expectedVersion int) error {
+	result, err := r.collection().DeleteOne(context.TODO(), versionFilter(id, expectedVersion))
+	if err != nil {
+		return err
+	}
+	if result.DeletedCount == 0 {
+		return ErrVersionConflict
+	}
+	return nil
+}
+
+// ReplacePostID points all post components referencing one post to another,
+// as when duplicate posts are merged. It returns the number of userpages changed.
+func (r *UserpageRepository) ReplacePostID(fromPostID, toPostID primitive.ObjectID) (int, error) {
+	result, err := r.collection().UpdateMany(
+		context.TODO(),
+		bson.M{"components.post.post_id": fromPostID},
+		bson.M{"$set": bson.M{"components.$[c].post.post_id": toPostID}},
+		options.Update().SetArrayFilters(options.ArrayFilters{
+			Filters: []interface{}{bson.M{"c.post.post_id": fromPostID}},
+		}),
+	)
+	if err != nil {
+		return 0, err
+	}
+	return int(result.ModifiedCount), nil
+}
@@ -0,0 +1,115 @@
+package repositories
+
+import (
+	"context"
+	"sane-discourse-backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type UserpageRevisionRepository struct {
+	client *mongo.Client
+}
+
+func NewUserpageRevisionRepository(client *mongo.Client) *UserpageRevisionRepository {
+	return &UserpageRevisionRepository{
+		client: client,
+	}
+}
+
+func (r *UserpageRevisionRepository) collection() *mongo.Collection {
+	return r.client.Database("sane_discourse").Collection("userpage_revisions")
+}
+
+func (r *UserpageRevisionRepository) EnsureIndexes() error {
+	_, err := r.collection().Indexes().CreateOne(context.TODO(), mongo.IndexModel{
+		Keys:    bson.D{{Key: "userpage_id", Value: 1}, {Key: "version", Value: -1}},
+		Options: options.Index().SetUnique(true),
+	})
+	return err
+}
+
+// Create stores the revision unless one for the same page version exists,
+// so a retried edit doesn't store the replaced version twice.
+func (r *UserpageRevisionRepository) Create(revision models.UserpageRevision) error {
+	filter := bson.M{
+		"userpage_id": revision.UserpageID,
+		"version":     revision.Version,
+	}
+	_, err := r.collection().UpdateOne(
+		context.TODO(),
+		filter,
+		bson.M{"$setOnInsert": revision},
+		options.Update().SetUpsert(true),
+	)
+	return err
+}
+
+func (r *UserpageRevisionRepository) FindByVersion(userpageID primitive.ObjectID, version int) (*models.UserpageRevision, error) {
+	var revision models.UserpageRevision
+	err := r.collection().FindOne(context.TODO(), bson.M{
+		"userpage_id": userpageID,
+		"version":     version,
+	}).Decode(&revision)
+	if err != nil {
+		return nil, err
+	}
+	return &revision, nil
+}
+
+// FindByUserpageID lists the revisions of a page older than beforeVersion,
+// newest first, without their components.
+func (r *UserpageRevisionRepository) FindByUserpageID(userpageID primitive.ObjectID, beforeVersion int, limit int) ([]models.UserpageRevision, error) {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "version", Value: -1}}).
+		SetLimit(int64(limit)).
+		SetProjection(bson.M{"components": 0})
+	cursor, err := r.collection().Find(context.TODO(), bson.M{
+		"userpage_id": userpageID,
+		"version":     bson.M{"$lt": beforeVersion},
+	}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.TODO())
+
+	revisions := []models.UserpageRevision{}
+	for cursor.Next(context.TODO()) {
+		var revision models.UserpageRevision
+		if err := cursor.Decode(&revision); err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, revision)
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	return revisions, nil
+}
+
+func (r *UserpageRevisionRepository) DeleteByUserpageID(userpageID primitive.ObjectID) error {
+	_, err := r.collection().DeleteMany(context.TODO(), bson.M{"userpage_id": userpageID})
+	return err
+}
+
+// ReplacePostID points all post components referencing one post to another,
+// as when duplicate posts are merged. It returns the number of revisions changed.
+func (r *UserpageRevisionRepository) ReplacePostID(fromPostID, toPostID primitive.ObjectID) (int, error) {
+	result, err := r.collection().UpdateMany(
+		context.TODO(),
+		bson.M{"components.post.post_id": fromPostID},
+		bson.M{"$set": bson.M{"components.$[c].post.post_id": toPostID}},
+		options.Update().SetArrayFilters(options.ArrayFilters{
+			Filters: []interface{}{bson.M{"c.post.post_id": fromPostID}},
+		}),
+	)
+	if err != nil {
+		return 0, err
+	}
+	return int(result.ModifiedCount), nil
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"sane-discourse-backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MaxBatchOperations caps the number of operations in one batch edit.
+const MaxBatchOperations = 500
+
+var (
+	ErrUnknownOperation = errors.New("unknown operation, must be add, update, delete or move")
+	ErrMissingComponent = errors.New("operation needs a component")
+	ErrEmptyBatch       = errors.New("batch has no operations")
+	ErrBatchTooLarge    = fmt.Errorf("batch has more than %d operations", MaxBatchOperations)
+)
+
+type ComponentOperationType string
+
+const (
+	ComponentOperationAdd    ComponentOperationType = "add"
+	ComponentOperationUpdate ComponentOperationType = "update"
+	ComponentOperationDelete ComponentOperationType = "delete"
+	ComponentOperationMove   ComponentOperationType = "move"
+)
+
+// ComponentOperation is one edit of a userpage. Ref addresses the component
+// for update, delete and move; Position is where add and move put it.
+type ComponentOperation struct {
+	Type      ComponentOperationType
+	Ref       ComponentRef
+	Position  ComponentPosition
+	Component *models.Component
+}
+
+// apply returns the components with the operation applied. The given slice
+// may be modified as well.
+func (op ComponentOperation) apply(components []models.Component) ([]models.Component, error) {
+	switch op.Type {
+	case ComponentOperationAdd:
+		if op.Component == nil {
+			return nil, ErrMissingComponent
+		}
+		return addComponent(components, op.Position, *op.Component)
+	case ComponentOperationUpdate:
+		if op.Component == nil {
+			return nil, ErrMissingComponent
+		}
+		return updateComponent(components, op.Ref, *op.Component)
+	case ComponentOperationDelete:
+		return deleteComponent(components, op.Ref)
+	case ComponentOperationMove:
+		return moveComponent(components, op.Ref, op.Position)
+	default:
+		return nil, ErrUnknownOperation
+	}
+}
+
+// addComponent inserts component at position. The component keeps an ID
+// chosen by the client if no other component on the page has it, so later
+// operations in the same batch can refer to it; otherwise it gets a new one.
+func addComponent(components []models.Component, position ComponentPosition, component models.Component) ([]models.Component, error) {
+	index, err := position.resolve(components)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := indexOfComponent(components, component.ID); err != ErrComponentNotFound || component.ID.IsZero() {
+		component.ID = primitive.NewObjectID()
+	}
+	return insertComponent(components, index, component), nil
+}
+
+// updateComponent replaces the referenced component, keeping its ID.
+func updateComponent(components []models.Component, ref ComponentRef, component models.Component) ([]models.Component, error) {
+	index, err := ref.resolve(components)
+	if err != nil {
+		return nil, err
+	}
+	component.ID = components[index].ID
+	components[index] = component
+	return components, nil
+}
+
+func deleteComponent(components []models.Component, ref ComponentRef) ([]models.Component, error) {
+	index, err := ref.resolve(components)
+	if err != nil {
+		return nil, err
+	}
+	return removeComponent(components, index), nil
+}
+
+func moveComponent(components []models.Component, ref ComponentRef, position ComponentPosition) ([]models.Component, error) {
+	prevIndex, err := ref.resolve(components)
+	if err != nil {
+		return nil, err
+	}
+	newIndex, err := position.resolve(components)
+	if err != nil {
+		return nil, err
+	}
+	component := components[prevIndex]
+	components = removeComponent(components, prevIndex)
+	// Positions refer to the page before the component was taken out
+	if newIndex > prevIndex {
+		newIndex -= 1
+	}
+	return insertComponent(components, newIndex, component), nil
+}
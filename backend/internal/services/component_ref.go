@@ -0,0 +1,74 @@
+package services
+
+import (
+	"errors"
+	"sane-discourse-backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var ErrComponentNotFound = errors.New("component not found")
+
+// ComponentRef addresses a component on a userpage. Components are looked
+// up by ID; Index is only used when ID is nil, for clients that predate
+// component IDs.
+type ComponentRef struct {
+	ID    *primitive.ObjectID
+	Index int
+}
+
+func (ref ComponentRef) resolve(components []models.Component) (int, error) {
+	if ref.ID == nil {
+		if ref.Index < 0 || ref.Index >= len(components) {
+			return 0, ErrInvalidComponentIndex
+		}
+		return ref.Index, nil
+	}
+	return indexOfComponent(components, *ref.ID)
+}
+
+// ComponentPosition is a place to insert a component: right before the
+// component with ID BeforeID, or at Index if BeforeID is nil. An Index equal
+// to the number of components appends to the page.
+type ComponentPosition struct {
+	BeforeID *primitive.ObjectID
+	Index    int
+}
+
+func (position ComponentPosition) resolve(components []models.Component) (int, error) {
+	if position.BeforeID == nil {
+		if position.Index < 0 || position.Index > len(components) {
+			return 0, ErrInvalidComponentIndex
+		}
+		return position.Index, nil
+	}
+	return indexOfComponent(components, *position.BeforeID)
+}
+
+func indexOfComponent(components []models.Component, id primitive.ObjectID) (int, error) {
+	for i, component := range components {
+		if component.ID == id {
+			return i, nil
+		}
+	}
+	return 0, ErrComponentNotFound
+}
+
+// insertComponent returns a new slice with component inserted at index,
+// leaving components untouched.
+func insertComponent(components []models.Component, index int, component models.Component) []models.Component {
+	newComponents := make([]models.Component, len(components)+1)
+	copy(newComponents[:index], components[:index])
+	newComponents[index] = component
+	copy(newComponents[index+1:], components[index:])
+	return newComponents
+}
+
+// removeComponent returns a new slice without the component at index,
+// leaving components untouched.
+func removeComponent(components []models.Component, index int) []models.Component {
+	newComponents := make([]models.Component, len(components)-1)
+	copy(newComponents[:index], components[:index])
+	copy(newComponents[index:], components[index+1:])
+	return newComponents
+}
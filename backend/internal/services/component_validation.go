@@ -0,0 +1,275 @@
+package services
+
+import (
+	"fmt"
+	"net/url"
+	"sane-discourse-backend/internal/models"
+	"sane-discourse-backend/pkg/utils"
+	"strings"
+	"unicode/utf8"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	MaxComponents      = 500
+	MaxHeaderLength    = 200
+	MaxParagraphLength = 10000
+	MaxQuoteLength     = 2000
+	MaxCaptionLength   = 500
+	MaxTitleLength     = 200
+	MaxURLLength       = 2048
+	MaxLinks           = 50
+)
+
+// FieldError describes one invalid field. Field is the JSON path of the
+// field in the request, e.g. "operations[2].component.header.size".
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError lists every invalid field of a request, so clients can
+// show all problems at once.
+type ValidationError struct {
+	Errors []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, len(e.Errors))
+	for i, fieldError := range e.Errors {
+		messages[i] = fieldError.Field + ": " + fieldError.Message
+	}
+	return "invalid userpage edit: " + strings.Join(messages, "; ")
+}
+
+type fieldErrors []FieldError
+
+func (errs *fieldErrors) add(field, format string, args ...any) {
+	*errs = append(*errs, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+func (errs fieldErrors) err() error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return &ValidationError{Errors: errs}
+}
+
+// validateOperations checks the components of all operations, including
+// that referenced posts and sections exist, before anything is applied.
+func (s *UserpageService) validateOperations(userID primitive.ObjectID, operations []ComponentOperation, batch bool) error {
+	errs := fieldErrors{}
+	posts := []*componentRef{}
+	collections := []*componentRef{}
+	for i, operation := range operations {
+		if operation.Component == nil {
+			continue
+		}
+		field := "component"
+		if batch {
+			field = fmt.Sprintf("operations[%d].component", i)
+		}
+		validateComponent(field, *operation.Component, &errs)
+		if post := operation.Component.Post; post != nil && !post.PostID.IsZero() {
+			posts = append(posts, &componentRef{field: field + ".post", component: operation.Component})
+		}
+		if collection := operation.Component.Collection; collection != nil && !collection.UserID.IsZero() {
+			if collection.UserID == userID {
+				errs.add(field+".collection.user_id", "cannot embed a section of your own page")
+				continue
+			}
+			collections = append(collections, &componentRef{field: field + ".collection", component: operation.Component})
+		}
+	}
+	if err := s.validatePostRefs(posts, &errs); err != nil {
+		return err
+	}
+	if err := s.validateCollectionRefs(collections, &errs); err != nil {
+		return err
+	}
+	return errs.err()
+}
+
+// componentRef is a component that references another document, with the
+// field path to report if the reference is dangling.
+type componentRef struct {
+	field     string
+	component *models.Component
+}
+
+func (s *UserpageService) validatePostRefs(refs []*componentRef, errs *fieldErrors) error {
+	if len(refs) == 0 {
+		return nil
+	}
+	postIDs := make([]primitive.ObjectID, len(refs))
+	for i, ref := range refs {
+		postIDs[i] = ref.component.Post.PostID
+	}
+	postsByID, err := s.postRepository.FindByIDs(postIDs)
+	if err != nil {
+		return err
+	}
+	for _, ref := range refs {
+		if _, ok := postsByID[ref.component.Post.PostID]; !ok {
+			errs.add(ref.field+".post_id", "post does not exist")
+		}
+	}
+	return nil
+}
+
+func (s *UserpageService) validateCollectionRefs(refs []*componentRef, errs *fieldErrors) error {
+	if len(refs) == 0 {
+		return nil
+	}
+	userIDs := make([]primitive.ObjectID, len(refs))
+	for i, ref := range refs {
+		userIDs[i] = ref.component.Collection.UserID
+	}
+	userpagesByUserID, err := s.userpageRepository.FindByUserIDs(userIDs)
+	if err != nil {
+		return err
+	}
+	for _, ref := range refs {
+		collection := ref.component.Collection
+		userpages, ok := userpagesByUserID[collection.UserID]
+		if !ok {
+			errs.add(ref.field+".user_id", "user has no page")
+			continue
+		}
+		if _, ok := findSection(userpages, collection.SectionID); !ok {
+			errs.add(ref.field+".section_id", "no header with this ID on the user's public pages")
+		}
+	}
+	return nil
+}
+
+func validateComponent(field string, component models.Component, errs *fieldErrors) {
+	variants := 0
+	if component.Header != nil {
+		variants++
+		validateHeader(field+".header", *component.Header, errs)
+	}
+	if component.Post != nil {
+		variants++
+		validatePost(field+".post", *component.Post, errs)
+	}
+	if component.Paragraph != nil {
+		variants++
+		validateParagraph(field+".paragraph", *component.Paragraph, errs)
+	}
+	if component.Divider != nil {
+		variants++
+		validateDivider(field+".divider", *component.Divider, errs)
+	}
+	if component.Quote != nil {
+		variants++
+		validateQuote(field+".quote", *component.Quote, errs)
+	}
+	if component.LinkList != nil {
+		variants++
+		validateLinkList(field+".link_list", *component.LinkList, errs)
+	}
+	if component.Image != nil {
+		variants++
+		validateImage(field+".image", *component.Image, errs)
+	}
+	if component.Collection != nil {
+		variants++
+		validateCollection(field+".collection", *component.Collection, errs)
+	}
+	if variants != 1 {
+		errs.add(field, "exactly one component type must be set, got %d", variants)
+	}
+}
+
+func validateHeader(field string, header models.HeaderComponent, errs *fieldErrors) {
+	if strings.TrimSpace(header.Content) == "" {
+		errs.add(field+".content", "must not be empty")
+	}
+	validateLength(field+".content", header.Content, MaxHeaderLength, errs)
+	if header.Size < models.HeaderComponentSizeLarge || header.Size > models.HeaderComponentSizeVerySmall {
+		errs.add(field+".size", "must be between %d and %d", models.HeaderComponentSizeLarge, models.HeaderComponentSizeVerySmall)
+	}
+}
+
+func validatePost(field string, post models.PostComponent, errs *fieldErrors) {
+	if post.PostID.IsZero() {
+		errs.add(field+".post_id", "must be set")
+	}
+	if post.Size < models.PostComponentSizeLarge || post.Size > models.PostComponentSizeSmall {
+		errs.add(field+".size", "must be between %d and %d", models.PostComponentSizeLarge, models.PostComponentSizeSmall)
+	}
+}
+
+func validateParagraph(field string, paragraph models.PragraphComponent, errs *fieldErrors) {
+	validateLength(field+".content", paragraph.Content, MaxParagraphLength, errs)
+	for _, target := range utils.MarkdownLinkTargets(paragraph.Content) {
+		if !utils.IsSafeLinkURL(target) {
+			errs.add(field+".content", "link target %q must be an http, https or mailto URL", target)
+		}
+	}
+}
+
+func validateDivider(field string, divider models.DividerComponent, errs *fieldErrors) {
+	if !divider.Style.IsValid() {
+		errs.add(field+".style", "unknown divider style %q", divider.Style)
+	}
+}
+
+func validateQuote(field string, quote models.QuoteComponent, errs *fieldErrors) {
+	if strings.TrimSpace(quote.Content) == "" {
+		errs.add(field+".content", "must not be empty")
+	}
+	validateLength(field+".content", quote.Content, MaxQuoteLength, errs)
+	validateLength(field+".attribution", quote.Attribution, MaxTitleLength, errs)
+}
+
+func validateLinkList(field string, linkList models.LinkListComponent, errs *fieldErrors) {
+	validateLength(field+".title", linkList.Title, MaxTitleLength, errs)
+	if len(linkList.Links) == 0 {
+		errs.add(field+".links", "must not be empty")
+	}
+	if len(linkList.Links) > MaxLinks {
+		errs.add(field+".links", "must have at most %d links", MaxLinks)
+	}
+	for i, link := range linkList.Links {
+		linkField := fmt.Sprintf("%s.links[%d]", field, i)
+		validateWebURL(linkField+".url", link.URL, errs)
+		validateLength(linkField+".title", link.Title, MaxTitleLength, errs)
+	}
+}
+
+func validateImage(field string, image models.ImageComponent, errs *fieldErrors) {
+	validateWebURL(field+".url", image.URL, errs)
+	validateLength(field+".caption", image.Caption, MaxCaptionLength, errs)
+	validateLength(field+".alt", image.Alt, MaxCaptionLength, errs)
+}
+
+func validateCollection(field string, collection models.CollectionComponent, errs *fieldErrors) {
+	if collection.UserID.IsZero() {
+		errs.add(field+".user_id", "must be set")
+	}
+	if collection.SectionID.IsZero() {
+		errs.add(field+".section_id", "must be set")
+	}
+}
+
+func validateLength(field string, value string, max int, errs *fieldErrors) {
+	if utf8.RuneCountInString(value) > max {
+		errs.add(field, "must be at most %d characters", max)
+	}
+}
+
+// validateWebURL accepts absolute http and https URLs only, so pages can't
+// link to javascript: or data: URLs.
+func validateWebURL(field string, rawURL string, errs *fieldErrors) {
+	if len(rawURL) > MaxURLLength {
+		errs.add(field, "must be at most %d characters", MaxURLLength)
+		return
+	}
+	parsed, err := url.Parse(rawURL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		errs.add(field, "must be an http or https URL")
+	}
+}
@@ -0,0 +1,145 @@
+package services
+
+import (
+	"errors"
+	"sane-discourse-backend/internal/repositories"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var ErrUnknownFeedRanking = errors.New("unknown feed ranking")
+
+// FeedRanker is a strategy for ordering the global feed. Rankers either
+// sort by an indexed field, or compute a score for a bounded set of
+// candidate posts; see repositories.RankQuery.
+type FeedRanker interface {
+	Query(now time.Time) repositories.RankQuery
+}
+
+const DefaultFeedRanking = "top"
+
+var feedRankers = map[string]FeedRanker{
+	"top":           topRanker{},
+	"newest":        newestRanker{},
+	"hot":           hotRanker{gravity: 1.8, window: 7 * 24 * time.Hour},
+	"important":     importantRanker{weight: 3},
+	"controversial": controversialRanker{},
+}
+
+// FeedRankerFor returns the ranker registered under name, or the default
+// ranker if name is empty.
+func FeedRankerFor(name string) (FeedRanker, error) {
+	if name == "" {
+		name = DefaultFeedRanking
+	}
+	ranker, ok := feedRankers[name]
+	if !ok {
+		return nil, ErrUnknownFeedRanking
+	}
+	return ranker, nil
+}
+
+// reactionField reads a materialized reaction counter, treating posts
+// without counters as having no reactions.
+func reactionField(path string) bson.M {
+	return bson.M{"$ifNull": bson.A{"$reactions." + path, 0}}
+}
+
+func createdAt() bson.M {
+	return bson.M{"$toDate": "$_id"}
+}
+
+// byReactionCount, byImportance and byNewest are the orders backed by the
+// post indexes.
+var (
+	byReactionCount = bson.D{{Key: "reactions.total", Value: -1}, {Key: "_id", Value: -1}}
+	byImportance    = bson.D{{Key: "reactions.scores.importance", Value: -1}, {Key: "_id", Value: -1}}
+	byNewest        = bson.D{{Key: "_id", Value: -1}}
+)
+
+// topRanker orders by the number of users who reacted at all.
+type topRanker struct{}
+
+func (topRanker) Query(now time.Time) repositories.RankQuery {
+	return repositories.RankQuery{Sort: byReactionCount}
+}
+
+// newestRanker orders by creation time, newest first.
+type newestRanker struct{}
+
+func (newestRanker) Query(now time.Time) repositories.RankQuery {
+	return repositories.RankQuery{Sort: byNewest}
+}
+
+// hotRanker divides engagement by a power of the post's age in hours, so
+// posts need a steady stream of reactions to stay near the top. Posts
+// older than window are not ranked at all.
+type hotRanker struct {
+	gravity float64
+	window  time.Duration
+}
+
+func (r hotRanker) Query(now time.Time) repositories.RankQuery {
+	points := bson.M{"$add": bson.A{reactionField("total"), reactionField("scores.vote")}}
+	ageHours := bson.M{"$divide": bson.A{bson.M{"$subtract": bson.A{now, createdAt()}}, 3600000}}
+	return repositories.RankQuery{
+		Sort:       byNewest,
+		Candidates: bson.M{"_id": bson.M{"$gte": primitive.NewObjectIDFromTimestamp(now.Add(-r.window))}},
+		Score: bson.M{"$divide": bson.A{
+			points,
+			bson.M{"$pow": bson.A{bson.M{"$add": bson.A{ageHours, 2}}, r.gravity}},
+		}},
+	}
+}
+
+// importantRanker orders the posts rated most important by the importance
+// axis, with votes as a weaker signal.
+type importantRanker struct {
+	weight int
+}
+
+func (r importantRanker) Query(now time.Time) repositories.RankQuery {
+	return repositories.RankQuery{
+		Sort:       byImportance,
+		Candidates: bson.M{"reactions.scores.importance": bson.M{"$gt": 0}},
+		Score: bson.M{"$add": bson.A{
+			bson.M{"$multiply": bson.A{reactionField("scores.importance"), r.weight}},
+			reactionField("scores.vote"),
+		}},
+	}
+}
+
+// controversialRanker favours posts with many agree and disagree reactions
+// in similar numbers: the total is scaled by the minority/majority ratio.
+// Posts without both are not ranked, and only the most reacted ones are
+// candidates, as no index orders posts by this score.
+type controversialRanker struct{}
+
+func (controversialRanker) Query(now time.Time) repositories.RankQuery {
+	agree := bson.M{"$add": bson.A{reactionField("counts.agree"), reactionField("counts.strong_agree")}}
+	disagree := bson.M{"$add": bson.A{reactionField("counts.disagree"), reactionField("counts.strong_disagree")}}
+	majority := bson.M{"$max": bson.A{"$$agree", "$$disagree"}}
+	minority := bson.M{"$min": bson.A{"$$agree", "$$disagree"}}
+	return repositories.RankQuery{
+		Sort: byReactionCount,
+		Candidates: bson.M{"$and": bson.A{
+			bson.M{"$or": bson.A{
+				bson.M{"reactions.counts.agree": bson.M{"$gt": 0}},
+				bson.M{"reactions.counts.strong_agree": bson.M{"$gt": 0}},
+			}},
+			bson.M{"$or": bson.A{
+				bson.M{"reactions.counts.disagree": bson.M{"$gt": 0}},
+				bson.M{"reactions.counts.strong_disagree": bson.M{"$gt": 0}},
+			}},
+		}},
+		Score: bson.M{"$let": bson.M{
+			"vars": bson.M{"agree": agree, "disagree": disagree},
+			"in": bson.M{"$multiply": bson.A{
+				bson.M{"$add": bson.A{"$$agree", "$$disagree"}},
+				bson.M{"$divide": bson.A{minority, majority}},
+			}},
+		}},
+	}
+}
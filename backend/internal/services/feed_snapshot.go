@@ -0,0 +1,101 @@
+package services
+
+import (
+	"sane-discourse-backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// maxFeedLength caps how many posts a feed whose ranking changes over time
+// can be paged through, which is also the size of its snapshot.
+const maxFeedLength = 1000
+
+// rankedPage returns one page of a feed whose ranking changes over time.
+// rank returns the current ranking cut to the given length. The first page
+// only loads limit+1 posts and stores nothing, its cursor remembers which
+// posts it showed. The second page stores the rest of the ranking as a
+// models.FeedSnapshot, which later pages are read from.
+func (s *PostService) rankedPage(scope, ranking string, after *pageCursor, limit int, rank func(length int) ([]primitive.ObjectID, error)) (*models.PostPage, error) {
+	if after != nil && !after.Snapshot.IsZero() {
+		return s.nextSnapshotPage(after, limit)
+	}
+	if after == nil {
+		postIDs, err := rank(limit + 1)
+		if err != nil {
+			return nil, err
+		}
+		if len(postIDs) <= limit {
+			return s.postPage(postIDs, "")
+		}
+		postIDs = postIDs[:limit]
+		return s.postPage(postIDs, encodeCursor(pageCursor{
+			Scope:   scope,
+			Ranking: ranking,
+			Seen:    postIDs,
+		}))
+	}
+	if len(after.Seen) == 0 {
+		return nil, ErrInvalidCursor
+	}
+
+	ranked, err := rank(maxFeedLength)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[primitive.ObjectID]bool, len(after.Seen))
+	for _, postID := range after.Seen {
+		seen[postID] = true
+	}
+	postIDs := []primitive.ObjectID{}
+	for _, postID := range ranked {
+		if !seen[postID] {
+			postIDs = append(postIDs, postID)
+		}
+	}
+	if len(postIDs) <= limit {
+		return s.postPage(postIDs, "")
+	}
+	snapshot, err := s.feedSnapshotRepository.Create(*models.NewFeedSnapshot(postIDs))
+	if err != nil {
+		return nil, err
+	}
+	return s.postPage(postIDs[:limit], encodeCursor(pageCursor{
+		Scope:    scope,
+		Ranking:  ranking,
+		Snapshot: snapshot.ID,
+		Offset:   limit,
+	}))
+}
+
+// nextSnapshotPage continues a feed in the snapshot its second page stored.
+func (s *PostService) nextSnapshotPage(after *pageCursor, limit int) (*models.PostPage, error) {
+	postIDs, err := s.feedSnapshotRepository.FindPostIDs(after.Snapshot, after.Offset, limit+1)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrExpiredCursor
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(postIDs) <= limit {
+		return s.postPage(postIDs, "")
+	}
+	next := *after
+	next.Offset += limit
+	return s.postPage(postIDs[:limit], encodeCursor(next))
+}
+
+// postPage loads the posts in the given order, skipping deleted ones.
+func (s *PostService) postPage(postIDs []primitive.ObjectID, nextCursor string) (*models.PostPage, error) {
+	postsByID, err := s.postRepository.FindByIDs(postIDs)
+	if err != nil {
+		return nil, err
+	}
+	page := &models.PostPage{Posts: []models.Post{}, NextCursor: nextCursor}
+	for _, postID := range postIDs {
+		if post, ok := postsByID[postID]; ok {
+			page.Posts = append(page.Posts, post)
+		}
+	}
+	return page, nil
+}
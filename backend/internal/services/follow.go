@@ -0,0 +1,78 @@
+package services
+
+import (
+	"errors"
+	"sane-discourse-backend/internal/models"
+	"sane-discourse-backend/internal/repositories"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+var ErrCannotFollowSelf = errors.New("users cannot follow themselves")
+
+type FollowService struct {
+	followRepository *repositories.FollowRepository
+	userRepository   *repositories.UserRepository
+}
+
+func NewFollowService(followRepository *repositories.FollowRepository, userRepository *repositories.UserRepository) *FollowService {
+	return &FollowService{
+		followRepository: followRepository,
+		userRepository:   userRepository,
+	}
+}
+
+func (s *FollowService) Follow(followerID, followeeID primitive.ObjectID) (*models.Follow, error) {
+	if followerID == followeeID {
+		return nil, ErrCannotFollowSelf
+	}
+	_, err := s.userRepository.FindByID(followeeID)
+	if err != nil {
+		return nil, err
+	}
+	follow := models.NewFollow(followerID, followeeID)
+	return s.followRepository.Create(*follow)
+}
+
+func (s *FollowService) Unfollow(followerID, followeeID primitive.ObjectID) error {
+	return s.followRepository.Delete(followerID, followeeID)
+}
+
+func (s *FollowService) GetFollowers(userID primitive.ObjectID) ([]models.UserProfile, error) {
+	follows, err := s.followRepository.FindByFolloweeID(userID)
+	if err != nil {
+		return nil, err
+	}
+	userIDs := make([]primitive.ObjectID, len(follows))
+	for i, follow := range follows {
+		userIDs[i] = follow.FollowerID
+	}
+	return s.profiles(userIDs)
+}
+
+func (s *FollowService) GetFollowing(userID primitive.ObjectID) ([]models.UserProfile, error) {
+	follows, err := s.followRepository.FindByFollowerID(userID)
+	if err != nil {
+		return nil, err
+	}
+	userIDs := make([]primitive.ObjectID, len(follows))
+	for i, follow := range follows {
+		userIDs[i] = follow.FolloweeID
+	}
+	return s.profiles(userIDs)
+}
+
+func (s *FollowService) profiles(userIDs []primitive.ObjectID) ([]models.UserProfile, error) {
+	profiles := []models.UserProfile{}
+	if len(userIDs) == 0 {
+		return profiles, nil
+	}
+	users, err := s.userRepository.FindByIDs(userIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, user := range users {
+		profiles = append(profiles, *user.ToProfile())
+	}
+	return profiles, nil
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
+)
+
+var (
+	ErrInvalidCursor = errors.New("invalid cursor")
+	ErrExpiredCursor = errors.New("cursor expired, load the first page again")
+	ErrInvalidLimit  = errors.New("limit must be between 1 and 100")
+)
+
+// pageCursor is the state needed to continue a listing. Clients only ever
+// see it base64 encoded and must treat it as opaque.
+type pageCursor struct {
+	Scope   string `json:"c,omitempty"`
+	Ranking string `json:"r,omitempty"`
+	// ID is the post or reaction to continue after in listings whose order
+	// never changes. Other feeds list the posts their first page showed in
+	// Seen, and later continue at Offset in a snapshot of their ranking, see
+	// models.FeedSnapshot.
+	ID       primitive.ObjectID   `json:"id"`
+	Seen     []primitive.ObjectID `json:"s,omitempty"`
+	Snapshot primitive.ObjectID   `json:"f"`
+	Offset   int                  `json:"o,omitempty"`
+}
+
+func encodeCursor(cursor pageCursor) string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor returns nil for an empty cursor, i.e. the first page.
+func decodeCursor(encoded string) (*pageCursor, error) {
+	if encoded == "" {
+		return nil, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var cursor pageCursor
+	if err = json.Unmarshal(data, &cursor); err != nil || (cursor.ID.IsZero() && len(cursor.Seen) == 0 && cursor.Snapshot.IsZero()) || cursor.Offset < 0 {
+		return nil, ErrInvalidCursor
+	}
+	return &cursor, nil
+}
+
+// pageLimit applies the default to an unset limit and rejects the rest of
+// the out of range values.
+func pageLimit(limit int) (int, error) {
+	if limit == 0 {
+		return DefaultPageLimit, nil
+	}
+	if limit < 0 || limit > MaxPageLimit {
+		return 0, ErrInvalidLimit
+	}
+	return limit, nil
+}
@@ -0,0 +1,169 @@
+package services
+
+import (
+	"bytes"
+	"sane-discourse-backend/internal/models"
+	"sane-discourse-backend/pkg/types"
+	"sort"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	personalizedFeedRanking = "personalized"
+	// personalizationHistory caps how many of the viewer's most recent
+	// reactions are compared against other users.
+	personalizationHistory = 500
+	// maxComparedReactions caps how many reactions of other users on those
+	// posts are compared, most recent first.
+	maxComparedReactions = 5000
+	// maxTrustedUsers caps how many of the most trusted users' reactions
+	// are used to score posts.
+	maxTrustedUsers = 100
+	// trustedUserHistory caps how many of each trusted user's most recent
+	// reactions score posts.
+	trustedUserHistory = 200
+)
+
+// trustWeights rates how closely every other user agrees with the viewer.
+// For each post both reacted to, every axis on which both have a value counts
+// as an agreement if the signs match and as a disagreement otherwise. The
+// weight is (agreements - disagreements) / (agreements + disagreements + 2),
+// so a handful of shared reactions earns less trust than many. Users with no
+// positive weight are left out.
+func trustWeights(viewerID primitive.ObjectID, viewerReactions, postReactions []models.Reaction) map[primitive.ObjectID]float64 {
+	viewerByPost := make(map[primitive.ObjectID]models.Reaction, len(viewerReactions))
+	for _, reaction := range viewerReactions {
+		viewerByPost[reaction.PostID] = reaction
+	}
+
+	agreements := map[primitive.ObjectID]int{}
+	disagreements := map[primitive.ObjectID]int{}
+	for _, reaction := range postReactions {
+		viewerReaction, ok := viewerByPost[reaction.PostID]
+		if !ok || reaction.UserID == viewerID {
+			continue
+		}
+		for _, axis := range types.ReactionAxes {
+			mine, theirs := viewerReaction.Get(axis), reaction.Get(axis)
+			if mine == types.ReactionValueNone || theirs == types.ReactionValueNone {
+				continue
+			}
+			if (mine > 0) == (theirs > 0) {
+				agreements[reaction.UserID]++
+			} else {
+				disagreements[reaction.UserID]++
+			}
+		}
+	}
+
+	weights := map[primitive.ObjectID]float64{}
+	for userID, agreed := range agreements {
+		disagreed := disagreements[userID]
+		weight := float64(agreed-disagreed) / float64(agreed+disagreed+2)
+		if weight > 0 {
+			weights[userID] = weight
+		}
+	}
+	return weights
+}
+
+// mostTrusted keeps the limit users with the highest weight.
+func mostTrusted(weights map[primitive.ObjectID]float64, limit int) []primitive.ObjectID {
+	userIDs := make([]primitive.ObjectID, 0, len(weights))
+	for userID := range weights {
+		userIDs = append(userIDs, userID)
+	}
+	sort.Slice(userIDs, func(i, j int) bool {
+		if weights[userIDs[i]] != weights[userIDs[j]] {
+			return weights[userIDs[i]] > weights[userIDs[j]]
+		}
+		return bytes.Compare(userIDs[i][:], userIDs[j][:]) > 0
+	})
+	if len(userIDs) > limit {
+		userIDs = userIDs[:limit]
+	}
+	return userIDs
+}
+
+// endorsement is how strongly a single reaction recommends its post.
+func endorsement(reaction models.Reaction) float64 {
+	return float64(reaction.Agreement + reaction.Importance + reaction.Vote)
+}
+
+type scoredPost struct {
+	id    primitive.ObjectID
+	score float64
+}
+
+// getPersonalizedFeed ranks posts by the reactions of users who agree with
+// the viewer, each weighted by trustWeights. Posts the viewer already reacted
+// to are left out. Viewers who do not share enough reactions with anyone
+// get the global feed instead.
+func (s *PostService) getPersonalizedFeed(viewerID primitive.ObjectID, after *pageCursor, limit int) (*models.PostPage, error) {
+	if after != nil && !after.Snapshot.IsZero() {
+		return s.nextSnapshotPage(after, limit)
+	}
+
+	viewerReactions, err := s.reactionRepository.FindRecentByUserID(viewerID, personalizationHistory)
+	if err != nil {
+		return nil, err
+	}
+	reactedPostIDs := make([]primitive.ObjectID, len(viewerReactions))
+	reacted := make(map[primitive.ObjectID]bool, len(viewerReactions))
+	for i, reaction := range viewerReactions {
+		reactedPostIDs[i] = reaction.PostID
+		reacted[reaction.PostID] = true
+	}
+
+	var weights map[primitive.ObjectID]float64
+	if len(viewerReactions) > 0 {
+		postReactions, err := s.reactionRepository.FindRecentByPostIDs(reactedPostIDs, viewerID, maxComparedReactions)
+		if err != nil {
+			return nil, err
+		}
+		weights = trustWeights(viewerID, viewerReactions, postReactions)
+	}
+	if len(weights) == 0 {
+		return s.getRankedFeed(FeedScopePersonalized, bson.M{}, DefaultFeedRanking, after, limit)
+	}
+	if after != nil && after.Ranking != personalizedFeedRanking {
+		return nil, ErrInvalidCursor
+	}
+
+	trustedReactions, err := s.reactionRepository.FindRecentByUserIDs(mostTrusted(weights, maxTrustedUsers), trustedUserHistory)
+	if err != nil {
+		return nil, err
+	}
+	scores := map[primitive.ObjectID]float64{}
+	for _, reaction := range trustedReactions {
+		if reacted[reaction.PostID] {
+			continue
+		}
+		scores[reaction.PostID] += weights[reaction.UserID] * endorsement(reaction)
+	}
+
+	ranked := []scoredPost{}
+	for postID, score := range scores {
+		if score > 0 {
+			ranked = append(ranked, scoredPost{id: postID, score: score})
+		}
+	}
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].score != ranked[j].score {
+			return ranked[i].score > ranked[j].score
+		}
+		return bytes.Compare(ranked[i].id[:], ranked[j].id[:]) > 0
+	})
+	return s.rankedPage(FeedScopePersonalized, personalizedFeedRanking, after, limit, func(length int) ([]primitive.ObjectID, error) {
+		if len(ranked) > length {
+			ranked = ranked[:length]
+		}
+		postIDs := make([]primitive.ObjectID, len(ranked))
+		for i, scored := range ranked {
+			postIDs[i] = scored.id
+		}
+		return postIDs, nil
+	})
+}
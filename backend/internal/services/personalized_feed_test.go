@@ -0,0 +1,114 @@
+package services
+
+import (
+	"sane-discourse-backend/internal/models"
+	"sane-discourse-backend/pkg/types"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// objectID returns a fixed ID, so that tie-breaks by ID are predictable.
+func objectID(n byte) primitive.ObjectID {
+	return primitive.ObjectID{11: n}
+}
+
+func reaction(userID, postID primitive.ObjectID, agreement, importance, vote types.ReactionValue) models.Reaction {
+	return models.Reaction{
+		UserID:     userID,
+		PostID:     postID,
+		Agreement:  agreement,
+		Importance: importance,
+		Vote:       vote,
+	}
+}
+
+func TestTrustWeights(t *testing.T) {
+	viewer, alice, bob := objectID(1), objectID(2), objectID(3)
+	first, second, unseen := objectID(10), objectID(11), objectID(12)
+	viewerReactions := []models.Reaction{
+		reaction(viewer, first, 1, 2, 0),
+		reaction(viewer, second, -1, 0, 1),
+	}
+
+	tests := []struct {
+		name          string
+		postReactions []models.Reaction
+		expected      map[primitive.ObjectID]float64
+	}{
+		{"no shared reactions", []models.Reaction{
+			reaction(alice, unseen, 1, 1, 1),
+		}, map[primitive.ObjectID]float64{}},
+		{"agreeing on one axis", []models.Reaction{
+			reaction(alice, first, 2, 0, 0),
+		}, map[primitive.ObjectID]float64{alice: 1.0 / 3}},
+		{"agreeing on every shared axis", []models.Reaction{
+			reaction(alice, first, 1, 1, -2),
+			reaction(alice, second, -2, 2, 1),
+		}, map[primitive.ObjectID]float64{alice: 4.0 / 6}},
+		{"more shared reactions earn more trust", []models.Reaction{
+			reaction(alice, first, 1, 1, 0),
+			reaction(alice, second, -1, 0, 1),
+			reaction(bob, first, 1, 0, 0),
+		}, map[primitive.ObjectID]float64{alice: 4.0 / 6, bob: 1.0 / 3}},
+		{"disagreements cancel agreements", []models.Reaction{
+			reaction(alice, first, 1, -1, 0),
+			reaction(bob, first, -1, -1, 0),
+		}, map[primitive.ObjectID]float64{}},
+		{"the viewer's own reactions", []models.Reaction{
+			reaction(viewer, first, 1, 2, 0),
+		}, map[primitive.ObjectID]float64{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			weights := trustWeights(viewer, viewerReactions, test.postReactions)
+			assert.Equal(t, len(test.expected), len(weights))
+			for userID, expected := range test.expected {
+				assert.InDelta(t, expected, weights[userID], 1e-9)
+			}
+		})
+	}
+}
+
+func TestMostTrusted(t *testing.T) {
+	alice, bob, carol := objectID(1), objectID(2), objectID(3)
+	tests := []struct {
+		name     string
+		weights  map[primitive.ObjectID]float64
+		limit    int
+		expected []primitive.ObjectID
+	}{
+		{"highest weight first", map[primitive.ObjectID]float64{alice: 0.2, bob: 0.5, carol: 0.3},
+			3, []primitive.ObjectID{bob, carol, alice}},
+		{"cut at limit", map[primitive.ObjectID]float64{alice: 0.2, bob: 0.5, carol: 0.3},
+			2, []primitive.ObjectID{bob, carol}},
+		{"ties by ID", map[primitive.ObjectID]float64{alice: 0.5, bob: 0.5, carol: 0.1},
+			2, []primitive.ObjectID{bob, alice}},
+		{"nobody", map[primitive.ObjectID]float64{},
+			2, []primitive.ObjectID{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, mostTrusted(test.weights, test.limit))
+		})
+	}
+}
+
+func TestEndorsement(t *testing.T) {
+	tests := []struct {
+		name     string
+		reaction models.Reaction
+		expected float64
+	}{
+		{"no reaction", reaction(objectID(1), objectID(2), 0, 0, 0), 0},
+		{"all positive", reaction(objectID(1), objectID(2), 1, 2, 1), 4},
+		{"mixed", reaction(objectID(1), objectID(2), -2, 1, 0), -1},
+		{"all negative", reaction(objectID(1), objectID(2), -1, -1, -2), -4},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, endorsement(test.reaction))
+		})
+	}
+}
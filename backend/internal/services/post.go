@@ -1,32 +1,85 @@
 package services
 
 import (
+	"errors"
+	"log"
 	"sane-discourse-backend/internal/models"
 	"sane-discourse-backend/internal/repositories"
 	"sane-discourse-backend/pkg/types"
 	"sane-discourse-backend/pkg/utils"
+	"time"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"golang.org/x/sync/singleflight"
 )
 
 type PostService struct {
-	postRepository     *repositories.PostRepository
-	userRepository     *repositories.UserRepository
-	reactionRepository *repositories.ReactionRepository
+	postRepository         *repositories.PostRepository
+	userRepository         *repositories.UserRepository
+	reactionRepository     *repositories.ReactionRepository
+	addedPostRepository    *repositories.AddedPostRepository
+	followRepository       *repositories.FollowRepository
+	linkPreviewRepository  *repositories.LinkPreviewRepository
+	feedSnapshotRepository *repositories.FeedSnapshotRepository
+
+	// scrapes deduplicates concurrent fetches of the same link
+	scrapes singleflight.Group
 }
 
 func NewPostService(
 	postRepo *repositories.PostRepository,
 	userRepo *repositories.UserRepository,
-	reactionRepo *repositories.ReactionRepository) *PostService {
+	reactionRepo *repositories.ReactionRepository,
+	addedPostRepo *repositories.AddedPostRepository,
+	followRepo *repositories.FollowRepository,
+	linkPreviewRepo *repositories.LinkPreviewRepository,
+	feedSnapshotRepo *repositories.FeedSnapshotRepository) *PostService {
 	return &PostService{
-		postRepository:     postRepo,
-		userRepository:     userRepo,
-		reactionRepository: reactionRepo,
+		postRepository:         postRepo,
+		userRepository:         userRepo,
+		reactionRepository:     reactionRepo,
+		addedPostRepository:    addedPostRepo,
+		followRepository:       followRepo,
+		linkPreviewRepository:  linkPreviewRepo,
+		feedSnapshotRepository: feedSnapshotRepo,
 	}
 }
 
+// CreatePost returns the post a link would become, without storing it.
+// Links that already are a post return that post, and links scraped in
+// the last models.LinkPreviewTTL return the cached preview. Concurrent
+// requests for the same link share a single fetch.
 func (s *PostService) CreatePost(url string) (*models.Post, error) {
+	canonicalURL, err := utils.NormalizeURL(url)
+	if err != nil {
+		return nil, err
+	}
+	if post, _ := s.postRepository.FindByCanonicalURL(canonicalURL); post != nil {
+		post.Reactions = nil
+		return post, nil
+	}
+	if preview, _ := s.linkPreviewRepository.FindByCanonicalURL(canonicalURL); preview != nil {
+		post := preview.Post
+		post.ID = primitive.NewObjectID()
+		return &post, nil
+	}
+
+	result, err, _ := s.scrapes.Do(canonicalURL, func() (any, error) {
+		return s.scrapePost(url, canonicalURL)
+	})
+	if err != nil {
+		return nil, err
+	}
+	// Callers sharing a fetch must not share the post
+	post := *result.(*models.Post)
+	return &post, nil
+}
+
+// scrapePost fetches a link and caches the preview under both the
+// requested and the scraped canonical URL, which differ after redirects.
+func (s *PostService) scrapePost(url, canonicalURL string) (*models.Post, error) {
 	linkMetadata, err := utils.ScrapeMetadata(url)
 	if err != nil {
 		return nil, err
@@ -38,10 +91,21 @@ func (s *PostService) CreatePost(url string) (*models.Post, error) {
 		linkMetadata.ImageURL,
 		linkMetadata.SiteName,
 		linkMetadata.URL,
+		linkMetadata.CanonicalURL,
 		linkMetadata.Type,
 		linkMetadata.Author,
 	)
 
+	keys := []string{canonicalURL}
+	if linkMetadata.CanonicalURL != "" && linkMetadata.CanonicalURL != canonicalURL {
+		keys = append(keys, linkMetadata.CanonicalURL)
+	}
+	for _, key := range keys {
+		if err = s.linkPreviewRepository.Save(*models.NewLinkPreview(key, *post)); err != nil {
+			// The preview still works, it is just fetched again next time
+			log.Printf("CreatePost: Failed to cache preview of %s: %v", key, err)
+		}
+	}
 	return post, nil
 }
 
@@ -58,23 +122,39 @@ func (s *PostService) CreatePosts(urls []string) ([]*models.Post, error) {
 }
 
 func (s *PostService) AddPost(post models.Post, userId primitive.ObjectID) (*models.Post, error) {
+	if post.Type != "" && !post.Type.IsValid() {
+		return nil, ErrInvalidPostType
+	}
 	_, err := s.userRepository.FindByID(userId)
 	if err != nil {
 		return nil, err
 	}
-	newPost, _ := s.postRepository.FindByURL(post.URL)
+	// The same page is often linked with tracking parameters or through
+	// mobile subdomains, all of which should end up at one post.
+	post.CanonicalURL, err = utils.NormalizeURL(post.URL)
+	if err != nil {
+		return nil, err
+	}
+	newPost, _ := s.postRepository.FindByCanonicalURL(post.CanonicalURL)
 	if newPost == nil {
 		newPost, err = s.postRepository.Create(post)
+		if mongo.IsDuplicateKeyError(err) {
+			// Another user added the same page at the same time
+			newPost, err = s.postRepository.FindByCanonicalURL(post.CanonicalURL)
+		}
 		if err != nil {
 			return newPost, err
 		}
 		// addedPosts = append(addedPosts, *newPost)
 	}
-	reactions, _ := s.reactionRepository.FindByUserIDAndPostID(userId, newPost.ID)
-	if len(reactions) != 0 {
+	if _, err = s.addedPostRepository.Create(*models.NewAddedPost(userId, newPost.ID)); err != nil {
+		return nil, err
+	}
+	reaction, _ := s.reactionRepository.FindByUserIDAndPostID(userId, newPost.ID)
+	if reaction != nil {
 		return newPost, nil
 	}
-	reaction := models.NewReaction(
+	reaction = models.NewReaction(
 		types.ReactionTypeAgree,
 		userId,
 		newPost.ID,
@@ -83,12 +163,220 @@ func (s *PostService) AddPost(post models.Post, userId primitive.ObjectID) (*mod
 	return newPost, nil
 }
 
-func (s *PostService) GetUserPosts(userID primitive.ObjectID) ([]models.Post, error) {
-	posts, err := s.postRepository.FindPostsReactedByUser(userID)
-	return posts, err
+// GetPost returns a single post with its reaction summary, and the viewer's
+// reaction if viewerID is not zero.
+func (s *PostService) GetPost(postID, viewerID primitive.ObjectID) (*models.Post, error) {
+	post, err := s.postRepository.FindByID(postID)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrPostNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	posts := []models.Post{*post}
+	if err = s.attachReactions(posts, viewerID); err != nil {
+		return nil, err
+	}
+	return &posts[0], nil
+}
+
+func (s *PostService) GetUserPosts(userID primitive.ObjectID, cursor string, limit int) (*models.PostPage, error) {
+	limit, err := pageLimit(limit)
+	if err != nil {
+		return nil, err
+	}
+	after, err := decodeCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+	afterReactionID := primitive.NilObjectID
+	if after != nil {
+		afterReactionID = after.ID
+	}
+
+	posts, nextReactionID, err := s.postRepository.FindPostsReactedByUser(userID, afterReactionID, limit)
+	if err != nil {
+		return nil, err
+	}
+	if err = s.attachReactions(posts, userID); err != nil {
+		return nil, err
+	}
+	page := &models.PostPage{Posts: posts}
+	if !nextReactionID.IsZero() {
+		page.NextCursor = encodeCursor(pageCursor{ID: nextReactionID})
+	}
+	return page, nil
+}
+
+const (
+	FeedScopeGlobal       = "global"
+	FeedScopePersonalized = "personalized"
+	FeedScopeFollowing    = "following"
+)
+
+var (
+	ErrPostNotFound     = errors.New("post not found")
+	ErrInvalidPostType  = errors.New("invalid post type")
+	ErrUnknownFeedScope = errors.New("unknown feed scope")
+	ErrLoginRequired    = errors.New("login required")
+	ErrSortNotSupported = errors.New("sort is not supported for the personalized feed")
+)
+
+type FeedOptions struct {
+	// Scope selects which posts the feed contains, global if empty.
+	Scope string
+	// Ranking names a FeedRanker, see FeedRankerFor. The personalized feed
+	// has its own ranking and must not set it.
+	Ranking string
+	Cursor  string
+	Limit   int
+}
+
+// GetFeed returns one page of the feed. If viewerID is not zero, each post
+// also carries the viewer's own reaction.
+func (s *PostService) GetFeed(viewerID primitive.ObjectID, feedOptions FeedOptions) (*models.PostPage, error) {
+	limit, err := pageLimit(feedOptions.Limit)
+	if err != nil {
+		return nil, err
+	}
+	after, err := decodeCursor(feedOptions.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	if feedOptions.Scope == "" {
+		feedOptions.Scope = FeedScopeGlobal
+	}
+	if after != nil && after.Scope != feedOptions.Scope {
+		return nil, ErrInvalidCursor
+	}
+
+	var page *models.PostPage
+	switch feedOptions.Scope {
+	case FeedScopeGlobal:
+		page, err = s.getRankedFeed(FeedScopeGlobal, bson.M{}, feedOptions.Ranking, after, limit)
+	case FeedScopePersonalized:
+		if viewerID.IsZero() {
+			return nil, ErrLoginRequired
+		}
+		if feedOptions.Ranking != "" {
+			return nil, ErrSortNotSupported
+		}
+		page, err = s.getPersonalizedFeed(viewerID, after, limit)
+	case FeedScopeFollowing:
+		if viewerID.IsZero() {
+			return nil, ErrLoginRequired
+		}
+		page, err = s.getFollowingFeed(viewerID, feedOptions.Ranking, after, limit)
+	default:
+		return nil, ErrUnknownFeedScope
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err = s.attachReactions(page.Posts, viewerID); err != nil {
+		return nil, err
+	}
+	return page, nil
+}
+
+// getRankedFeed returns one page of the posts matching filter, ordered by
+// the named FeedRanker. Feeds whose order never changes continue after the
+// last post, the others are paged by rankedPage.
+func (s *PostService) getRankedFeed(scope string, filter bson.M, ranking string, after *pageCursor, limit int) (*models.PostPage, error) {
+	if ranking == "" {
+		ranking = DefaultFeedRanking
+	}
+	ranker, err := FeedRankerFor(ranking)
+	if err != nil {
+		return nil, err
+	}
+	if after != nil && after.Ranking != ranking {
+		return nil, ErrInvalidCursor
+	}
+
+	query := ranker.Query(time.Now())
+	if !query.IsStable() {
+		return s.rankedPage(scope, ranking, after, limit, func(length int) ([]primitive.ObjectID, error) {
+			return s.postRepository.FindRankedIDs(filter, query, length)
+		})
+	}
+
+	if after != nil {
+		if after.ID.IsZero() {
+			return nil, ErrInvalidCursor
+		}
+		filter = bson.M{"$and": bson.A{filter, bson.M{"_id": bson.M{"$lt": after.ID}}}}
+	}
+	postIDs, err := s.postRepository.FindRankedIDs(filter, query, limit+1)
+	if err != nil {
+		return nil, err
+	}
+	if len(postIDs) <= limit {
+		return s.postPage(postIDs, "")
+	}
+	postIDs = postIDs[:limit]
+	return s.postPage(postIDs, encodeCursor(pageCursor{
+		Scope:   scope,
+		Ranking: ranking,
+		ID:      postIDs[limit-1],
+	}))
 }
 
-func (s *PostService) GetFeed() ([]models.Post, error) {
-	posts, err := s.postRepository.FindAllSortedByReactionCount()
-	return posts, err
+// getFollowingFeed only contains posts that users the viewer follows added
+// or reacted to. Only their most recent adds and reactions are looked at,
+// up to maxFeedLength of each.
+func (s *PostService) getFollowingFeed(viewerID primitive.ObjectID, ranking string, after *pageCursor, limit int) (*models.PostPage, error) {
+	follows, err := s.followRepository.FindByFollowerID(viewerID)
+	if err != nil {
+		return nil, err
+	}
+	if len(follows) == 0 {
+		return &models.PostPage{Posts: []models.Post{}}, nil
+	}
+	followeeIDs := make([]primitive.ObjectID, len(follows))
+	for i, follow := range follows {
+		followeeIDs[i] = follow.FolloweeID
+	}
+	addedPostIDs, err := s.addedPostRepository.FindRecentPostIDsByUserIDs(followeeIDs, maxFeedLength)
+	if err != nil {
+		return nil, err
+	}
+	reactedPostIDs, err := s.reactionRepository.FindRecentPostIDsByUserIDs(followeeIDs, maxFeedLength)
+	if err != nil {
+		return nil, err
+	}
+	postIDs := append(addedPostIDs, reactedPostIDs...)
+	return s.getRankedFeed(FeedScopeFollowing, bson.M{"_id": bson.M{"$in": postIDs}}, ranking, after, limit)
+}
+
+// attachReactions makes sure every post carries a reaction summary and, if
+// viewerID is not zero, fills in the viewer's reactions with one query.
+func (s *PostService) attachReactions(posts []models.Post, viewerID primitive.ObjectID) error {
+	postIDs := make([]primitive.ObjectID, len(posts))
+	for i := range posts {
+		postIDs[i] = posts[i].ID
+		if posts[i].Reactions == nil {
+			posts[i].Reactions = models.NewReactionSummary()
+		}
+	}
+	if len(posts) == 0 || viewerID.IsZero() {
+		return nil
+	}
+
+	reactions, err := s.reactionRepository.FindByUserIDAndPostIDs(viewerID, postIDs)
+	if err != nil {
+		return err
+	}
+	myReactions := map[primitive.ObjectID]models.Reaction{}
+	for _, reaction := range reactions {
+		myReactions[reaction.PostID] = reaction
+	}
+	for i := range posts {
+		if reaction, ok := myReactions[posts[i].ID]; ok {
+			posts[i].MyReaction = &reaction
+		}
+	}
+	return nil
 }
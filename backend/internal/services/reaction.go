@@ -1,15 +1,83 @@
 package services
 
 import (
+	"errors"
+	"sane-discourse-backend/internal/models"
 	"sane-discourse-backend/internal/repositories"
+	"sane-discourse-backend/pkg/types"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	ErrInvalidReactionType  = errors.New("invalid reaction type")
+	ErrInvalidReactionAxis  = errors.New("invalid reaction axis")
+	ErrInvalidReactionValue = errors.New("reaction value must be between -2 and 2")
 )
 
 type ReactionService struct {
 	reactionRepository *repositories.ReactionRepository
+	postRepository     *repositories.PostRepository
 }
 
-func NewReactionService(reactionRepository *repositories.ReactionRepository) *ReactionService {
+func NewReactionService(reactionRepository *repositories.ReactionRepository, postRepository *repositories.PostRepository) *ReactionService {
 	return &ReactionService{
 		reactionRepository: reactionRepository,
+		postRepository:     postRepository,
+	}
+}
+
+// SetReactionType sets the axis named by reactionType to its value,
+// replacing whatever the user had on that axis before.
+func (s *ReactionService) SetReactionType(userID, postID primitive.ObjectID, reactionType types.ReactionType) (*models.Reaction, error) {
+	if !reactionType.IsValid() {
+		return nil, ErrInvalidReactionType
+	}
+	return s.SetReaction(userID, postID, reactionType.Axis(), reactionType.Value())
+}
+
+// SetReaction sets one axis of the user's reaction on a post. Setting the
+// last non-zero axis to zero removes the reaction, in which case nil is
+// returned.
+func (s *ReactionService) SetReaction(userID, postID primitive.ObjectID, axis types.ReactionAxis, value types.ReactionValue) (*models.Reaction, error) {
+	if !axis.IsValid() {
+		return nil, ErrInvalidReactionAxis
+	}
+	if !value.IsValid() {
+		return nil, ErrInvalidReactionValue
 	}
+	_, err := s.postRepository.FindByID(postID)
+	if err != nil {
+		return nil, err
+	}
+
+	reaction, err := s.reactionRepository.FindByUserIDAndPostID(userID, postID)
+	if err == mongo.ErrNoDocuments {
+		if value == types.ReactionValueNone {
+			return nil, nil
+		}
+		reaction = &models.Reaction{UserID: userID, PostID: postID}
+		reaction.Set(axis, value)
+		return s.reactionRepository.Create(*reaction)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	reaction.Set(axis, value)
+	if reaction.IsEmpty() {
+		return nil, s.reactionRepository.Delete(userID, postID)
+	}
+	return s.reactionRepository.Update(*reaction)
+}
+
+// RemoveReaction clears one axis of the user's reaction on a post and
+// returns what remains of it.
+func (s *ReactionService) RemoveReaction(userID, postID primitive.ObjectID, axis types.ReactionAxis) (*models.Reaction, error) {
+	return s.SetReaction(userID, postID, axis, types.ReactionValueNone)
+}
+
+func (s *ReactionService) GetPostReactions(postID primitive.ObjectID) ([]models.Reaction, error) {
+	return s.reactionRepository.FindByPostID(postID)
 }
@@ -1,45 +1,57 @@
 package services
 
 import (
+	"errors"
+	"fmt"
 	"sane-discourse-backend/internal/models"
 	"sane-discourse-backend/internal/repositories"
+	"sane-discourse-backend/pkg/utils"
+	"time"
 
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	ErrUserpageNotFound      = errors.New("userpage not found")
+	ErrUserpageConflict      = errors.New("userpage has been changed since it was loaded")
+	ErrInvalidComponentIndex = errors.New("component index out of range")
 )
 
 type UserpageService struct {
 	userpageRepository *repositories.UserpageRepository
+	revisionRepository *repositories.UserpageRevisionRepository
+	userRepository     *repositories.UserRepository
+	postRepository     *repositories.PostRepository
 }
 
-func NewUserpageService(userpageRepository *repositories.UserpageRepository) *UserpageService {
+func NewUserpageService(
+	userpageRepository *repositories.UserpageRepository,
+	revisionRepository *repositories.UserpageRevisionRepository,
+	userRepository *repositories.UserRepository,
+	postRepository *repositories.PostRepository) *UserpageService {
 	return &UserpageService{
 		userpageRepository: userpageRepository,
+		revisionRepository: revisionRepository,
+		userRepository:     userRepository,
+		postRepository:     postRepository,
 	}
 }
 
-func (s *UserpageService) AddComponent(userID primitive.ObjectID, index int, component *models.Component) (*models.Userpage, error) {
-	userpage, err := s.userpageRepository.FindByUserID(userID)
-	if err != nil {
-		return nil, err
-	}
-	// Insert component at index without modifying the slice during operation
-	newComponents := make([]models.Component, len(userpage.Components)+1)
-	copy(newComponents[:index], userpage.Components[:index])
-	newComponents[index] = *component
-	copy(newComponents[index+1:], userpage.Components[index:])
-	userpage.Components = newComponents
-	userpage, err = s.userpageRepository.Update(*userpage)
-	if err != nil {
-		return nil, err
-	}
-	return userpage, nil
+func (s *UserpageService) AddComponent(userID primitive.ObjectID, slug string, version int, position ComponentPosition, component *models.Component) (*models.Userpage, error) {
+	return s.applyOperations(userID, slug, version, []ComponentOperation{
+		{Type: ComponentOperationAdd, Position: position, Component: component},
+	}, false)
 }
 
-func (s *UserpageService) GetUserpage(userID primitive.ObjectID) (*models.Userpage, error) {
-	userpage, err := s.userpageRepository.FindByUserID(userID)
+// GetUserpage returns the user's page with the given slug. The default
+// page is created if it doesn't exist yet.
+func (s *UserpageService) GetUserpage(userID primitive.ObjectID, slug string) (*models.Userpage, error) {
+	slug = pageSlug(slug)
+	userpage, err := s.userpageRepository.FindByUserIDAndSlug(userID, slug)
 	if err != nil {
 		// If userpage doesn't exist, create a new one with a default header
-		if err.Error() == "mongo: no documents in result" {
+		if err == mongo.ErrNoDocuments && slug == models.DefaultUserpageSlug {
 			defaultHeader := models.Component{
 				Header: &models.HeaderComponent{
 					Content: "My Page",
@@ -49,72 +61,322 @@ func (s *UserpageService) GetUserpage(userID primitive.ObjectID) (*models.Userpa
 			newUserpage := models.NewUserpage([]models.Component{defaultHeader}, userID)
 			return s.userpageRepository.Create(*newUserpage)
 		}
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrUserpageNotFound
+		}
 		return nil, err
 	}
-	return userpage, nil
+	// Pages stored before component IDs existed get them on first load
+	if userpage.EnsureComponentIDs() {
+		if err = s.userpageRepository.SaveComponentIDs(*userpage); err != nil {
+			return nil, err
+		}
+	}
+	return s.resolved(userpage)
+}
+
+func (s *UserpageService) UpdateComponent(userID primitive.ObjectID, slug string, version int, ref ComponentRef, component *models.Component) (*models.Userpage, error) {
+	return s.applyOperations(userID, slug, version, []ComponentOperation{
+		{Type: ComponentOperationUpdate, Ref: ref, Component: component},
+	}, false)
+}
+
+func (s *UserpageService) DeleteComponent(userID primitive.ObjectID, slug string, version int, ref ComponentRef) (*models.Userpage, error) {
+	return s.applyOperations(userID, slug, version, []ComponentOperation{
+		{Type: ComponentOperationDelete, Ref: ref},
+	}, false)
+}
+
+func (s *UserpageService) MoveComponent(userID primitive.ObjectID, slug string, version int, ref ComponentRef, position ComponentPosition) (*models.Userpage, error) {
+	return s.applyOperations(userID, slug, version, []ComponentOperation{
+		{Type: ComponentOperationMove, Ref: ref, Position: position},
+	}, false)
+}
+
+// ApplyOperations applies the operations in order and saves the page once,
+// so either all of them take effect or none do. Each operation sees the page
+// as left by the ones before it.
+func (s *UserpageService) ApplyOperations(userID primitive.ObjectID, slug string, version int, operations []ComponentOperation) (*models.Userpage, error) {
+	if len(operations) == 0 {
+		return nil, ErrEmptyBatch
+	}
+	if len(operations) > MaxBatchOperations {
+		return nil, ErrBatchTooLarge
+	}
+	return s.applyOperations(userID, slug, version, operations, true)
 }
 
-func (s *UserpageService) UpdateComponent(userID primitive.ObjectID, index int, component *models.Component) (*models.Userpage, error) {
-	userpage, err := s.userpageRepository.FindByUserID(userID)
+// applyOperations validates all operations up front and then applies them.
+// Errors of a batch name the operation they belong to.
+func (s *UserpageService) applyOperations(userID primitive.ObjectID, slug string, version int, operations []ComponentOperation, batch bool) (*models.Userpage, error) {
+	if err := s.validateOperations(userID, operations, batch); err != nil {
+		return nil, err
+	}
+	return s.mutate(userID, slug, version, func(userpage *models.Userpage) error {
+		components := userpage.Components
+		for i, operation := range operations {
+			var err error
+			components, err = operation.apply(components)
+			if err != nil {
+				if !batch {
+					return err
+				}
+				return fmt.Errorf("operation %d: %w", i, err)
+			}
+		}
+		if len(components) > MaxComponents && len(components) > len(userpage.Components) {
+			return &ValidationError{Errors: []FieldError{{
+				Field:   "components",
+				Message: fmt.Sprintf("a page can have at most %d components", MaxComponents),
+			}}}
+		}
+		userpage.Components = components
+		userpage.UndoVersion = &version
+		return nil
+	})
+}
+
+// mutate applies change to the user's page with the given slug and saves
+// it, provided the page is still at the given version. If it isn't, the
+// current page is returned together with ErrUserpageConflict so the client
+// can rebase its edit. The replaced version is kept as a revision.
+func (s *UserpageService) mutate(userID primitive.ObjectID, slug string, version int, change func(userpage *models.Userpage) error) (*models.Userpage, error) {
+	return s.save(userID, slug, version, true, change)
+}
+
+// mutateSettings is mutate for changes to anything but the components.
+// Revisions only hold components, so no revision is kept and Undo and
+// RestoreRevision leave these changes in place.
+func (s *UserpageService) mutateSettings(userID primitive.ObjectID, slug string, version int, change func(userpage *models.Userpage) error) (*models.Userpage, error) {
+	return s.save(userID, slug, version, false, change)
+}
+
+func (s *UserpageService) save(userID primitive.ObjectID, slug string, version int, keepRevision bool, change func(userpage *models.Userpage) error) (*models.Userpage, error) {
+	userpage, err := s.findUserpage(userID, slug)
 	if err != nil {
 		return nil, err
 	}
-	if index < 0 || index >= len(userpage.Components) {
+	if userpage.Version != version {
+		return s.conflict(userpage)
+	}
+	userpage.EnsureComponentIDs()
+	revision := models.NewUserpageRevision(*userpage)
+	if err = change(userpage); err != nil {
 		return nil, err
 	}
-	userpage.Components[index] = *component
-	userpage, err = s.userpageRepository.Update(*userpage)
+	if keepRevision {
+		if err = s.revisionRepository.Create(*revision); err != nil {
+			return nil, err
+		}
+	}
+	userpage.UpdatedAt = time.Now()
+	updated, err := s.userpageRepository.UpdateIfVersion(*userpage, version)
+	if errors.Is(err, repositories.ErrVersionConflict) {
+		current, err := s.userpageRepository.FindByID(userpage.ID)
+		if err != nil {
+			return nil, err
+		}
+		return s.conflict(current)
+	}
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, slugTaken()
+	}
 	if err != nil {
 		return nil, err
 	}
-	return userpage, nil
+	return s.resolved(updated)
 }
 
-func (s *UserpageService) DeleteComponent(userID primitive.ObjectID, index int) (*models.Userpage, error) {
-	userpage, err := s.userpageRepository.FindByUserID(userID)
+// findUserpage returns the user's page with the given slug, or
+// ErrUserpageNotFound. An empty slug means the default page.
+func (s *UserpageService) findUserpage(userID primitive.ObjectID, slug string) (*models.Userpage, error) {
+	userpage, err := s.userpageRepository.FindByUserIDAndSlug(userID, pageSlug(slug))
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrUserpageNotFound
+	}
 	if err != nil {
 		return nil, err
 	}
-	if index < 0 || index >= len(userpage.Components) {
+	return userpage, nil
+}
+
+func pageSlug(slug string) string {
+	if slug == "" {
+		return models.DefaultUserpageSlug
+	}
+	return slug
+}
+
+func (s *UserpageService) conflict(current *models.Userpage) (*models.Userpage, error) {
+	current, err := s.resolved(current)
+	if err != nil {
 		return nil, err
 	}
+	return current, ErrUserpageConflict
+}
 
-	newComponents := make([]models.Component, len(userpage.Components)-1)
-	copy(newComponents[:index], userpage.Components[:index])
-	copy(newComponents[index:], userpage.Components[index+1:])
-	userpage.Components = newComponents
-	userpage, err = s.userpageRepository.Update(*userpage)
+// GetPublicUserpage returns the page with the given slug of the user with
+// the given username, as seen by viewerID, with post components resolved to
+// their posts. viewerID is zero for anonymous viewers, and token is the
+// share token of the link they followed, if any. Pages the viewer may not
+// see are reported as not found.
+func (s *UserpageService) GetPublicUserpage(viewerID primitive.ObjectID, username string, slug string, token string) (*models.PublicUserpage, error) {
+	user, err := s.userRepository.FindByUsername(username)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrUserpageNotFound
+	}
 	if err != nil {
 		return nil, err
 	}
-	return userpage, nil
+	userpages, err := s.userpageRepository.FindAllByUserID(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	slug = pageSlug(slug)
+	var userpage *models.Userpage
+	pages := []models.UserpageSummary{}
+	for i := range userpages {
+		if userpages[i].Slug == slug && canView(&userpages[i], viewerID, token) {
+			userpage = &userpages[i]
+		}
+		// Only public pages are listed, except to the owner
+		if canView(&userpages[i], viewerID, "") {
+			pages = append(pages, *userpages[i].ToSummary())
+		}
+	}
+	if userpage == nil {
+		return nil, ErrUserpageNotFound
+	}
+
+	if err = s.resolveComponents(userpage.Components); err != nil {
+		return nil, err
+	}
+	return &models.PublicUserpage{
+		User:       *user.ToProfile(),
+		Slug:       userpage.Slug,
+		Title:      userpage.Title,
+		Visibility: userpage.Visibility,
+		Components: userpage.Components,
+		Pages:      pages,
+		UpdatedAt:  userpage.UpdatedAt,
+	}, nil
 }
 
-func (s *UserpageService) MoveComponent(userID primitive.ObjectID, prevIndex int, newIndex int) (*models.Userpage, error) {
-	userpage, err := s.userpageRepository.FindByUserID(userID)
+// resolveComponents fills in what components reference: the sections that
+// collection components embed, and the posts of post components both on the
+// page and in those sections. Each kind is loaded in a single query.
+// Paragraphs get their rendered HTML.
+func (s *UserpageService) resolveComponents(components []models.Component) error {
+	sectionComponents, err := s.resolveCollections(components)
+	if err != nil {
+		return err
+	}
+	all := make([]models.Component, 0, len(components)+len(sectionComponents))
+	all = append(all, components...)
+	all = append(all, sectionComponents...)
+	for _, component := range all {
+		if component.Paragraph != nil {
+			component.Paragraph.ContentHTML = utils.RenderMarkdown(component.Paragraph.Content)
+		}
+	}
+	return s.resolvePosts(all)
+}
+
+// resolveCollections loads the sections embedded by collection components
+// and returns all components of those sections. Collections in the loaded
+// sections are left unresolved.
+func (s *UserpageService) resolveCollections(components []models.Component) ([]models.Component, error) {
+	userIDs := []primitive.ObjectID{}
+	for _, component := range components {
+		if component.Collection != nil {
+			userIDs = append(userIDs, component.Collection.UserID)
+		}
+	}
+	if len(userIDs) == 0 {
+		return nil, nil
+	}
+	userpagesByUserID, err := s.userpageRepository.FindByUserIDs(userIDs)
+	if err != nil {
+		return nil, err
+	}
+	users, err := s.userRepository.FindByIDs(userIDs)
 	if err != nil {
 		return nil, err
 	}
-	component := userpage.Components[prevIndex]
-	// Remove component from prevIndex
-	newComponents := make([]models.Component, len(userpage.Components)-1)
-	copy(newComponents[:prevIndex], userpage.Components[:prevIndex])
-	copy(newComponents[prevIndex:], userpage.Components[prevIndex+1:])
+	usersByID := make(map[primitive.ObjectID]models.User, len(users))
+	for _, user := range users {
+		usersByID[user.ID] = user
+	}
 
-	// Adjust newIndex if necessary
-	if newIndex > prevIndex {
-		newIndex -= 1
+	sectionComponents := []models.Component{}
+	for _, component := range components {
+		collection := component.Collection
+		if collection == nil {
+			continue
+		}
+		user, userFound := usersByID[collection.UserID]
+		if !userFound {
+			collection.Missing = true
+			continue
+		}
+		section, found := findSection(userpagesByUserID[collection.UserID], collection.SectionID)
+		if !found {
+			collection.Missing = true
+			continue
+		}
+		collection.User = user.ToProfile()
+		collection.Components = section
+		sectionComponents = append(sectionComponents, section...)
 	}
+	return sectionComponents, nil
+}
 
-	// Insert component at newIndex
-	result := make([]models.Component, len(newComponents)+1)
-	copy(result[:newIndex], newComponents[:newIndex])
-	result[newIndex] = component
-	copy(result[newIndex+1:], newComponents[newIndex:])
+// findSection looks for the section with the given header on any of the
+// public pages among userpages. Sections of unlisted pages can't be
+// embedded, as that would show them to everyone.
+func findSection(userpages []models.Userpage, headerID primitive.ObjectID) ([]models.Component, bool) {
+	for i := range userpages {
+		if !canView(&userpages[i], primitive.NilObjectID, "") {
+			continue
+		}
+		if section, ok := userpages[i].Section(headerID); ok {
+			return section, true
+		}
+	}
+	return nil, false
+}
 
-	userpage.Components = result
-	userpage, err = s.userpageRepository.Update(*userpage)
+// resolvePosts loads the posts referenced by post components in one query
+// and flags components whose post has been deleted.
+func (s *UserpageService) resolvePosts(components []models.Component) error {
+	postIDs := []primitive.ObjectID{}
+	for _, component := range components {
+		if component.Post != nil {
+			postIDs = append(postIDs, component.Post.PostID)
+		}
+	}
+	if len(postIDs) == 0 {
+		return nil
+	}
+	postsByID, err := s.postRepository.FindByIDs(postIDs)
 	if err != nil {
+		return err
+	}
+	for _, component := range components {
+		if component.Post == nil {
+			continue
+		}
+		if post, ok := postsByID[component.Post.PostID]; ok {
+			component.Post.Post = &post
+		} else {
+			component.Post.Missing = true
+		}
+	}
+	return nil
+}
+
+func (s *UserpageService) resolved(userpage *models.Userpage) (*models.Userpage, error) {
+	if err := s.resolveComponents(userpage.Components); err != nil {
 		return nil, err
 	}
 	return userpage, nil
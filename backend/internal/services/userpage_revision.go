@@ -0,0 +1,94 @@
+package services
+
+import (
+	"errors"
+	"math"
+	"sane-discourse-backend/internal/models"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+var (
+	ErrRevisionNotFound = errors.New("revision not found")
+	ErrNothingToUndo    = errors.New("there is no earlier version to go back to")
+)
+
+// GetRevisions lists the earlier versions of one of the user's pages, newest first.
+// Only versions below beforeVersion are listed if it is positive.
+func (s *UserpageService) GetRevisions(userID primitive.ObjectID, slug string, beforeVersion int, limit int) ([]models.UserpageRevision, error) {
+	limit, err := pageLimit(limit)
+	if err != nil {
+		return nil, err
+	}
+	userpage, err := s.findUserpage(userID, slug)
+	if err != nil {
+		return nil, err
+	}
+	if beforeVersion <= 0 {
+		beforeVersion = math.MaxInt32
+	}
+	return s.revisionRepository.FindByUserpageID(userpage.ID, beforeVersion, limit)
+}
+
+// GetRevision returns one of the user's pages as it was at the given version, with
+// post components resolved, for previewing it before a restore.
+func (s *UserpageService) GetRevision(userID primitive.ObjectID, slug string, version int) (*models.UserpageRevision, error) {
+	userpage, err := s.findUserpage(userID, slug)
+	if err != nil {
+		return nil, err
+	}
+	revision, err := s.findRevision(userpage.ID, version)
+	if err != nil {
+		return nil, err
+	}
+	if err = s.resolveComponents(revision.Components); err != nil {
+		return nil, err
+	}
+	return revision, nil
+}
+
+// Undo reverts the last edit. Undoing repeatedly keeps stepping back through
+// earlier edits, as an undo doesn't count as an edit to be undone itself.
+func (s *UserpageService) Undo(userID primitive.ObjectID, slug string, version int) (*models.Userpage, error) {
+	return s.mutate(userID, slug, version, func(userpage *models.Userpage) error {
+		if userpage.UndoVersion == nil {
+			return ErrNothingToUndo
+		}
+		target, err := s.findRevision(userpage.ID, *userpage.UndoVersion)
+		if errors.Is(err, ErrRevisionNotFound) {
+			return ErrNothingToUndo
+		}
+		if err != nil {
+			return err
+		}
+		userpage.Components = target.Components
+		userpage.UndoVersion = target.UndoVersion
+		return nil
+	})
+}
+
+// RestoreRevision makes an earlier version the current one. The restore is
+// an edit like any other and can be undone.
+func (s *UserpageService) RestoreRevision(userID primitive.ObjectID, slug string, version int, revisionVersion int) (*models.Userpage, error) {
+	return s.mutate(userID, slug, version, func(userpage *models.Userpage) error {
+		revision, err := s.findRevision(userpage.ID, revisionVersion)
+		if err != nil {
+			return err
+		}
+		userpage.Components = revision.Components
+		userpage.UndoVersion = &version
+		return nil
+	})
+}
+
+func (s *UserpageService) findRevision(userpageID primitive.ObjectID, version int) (*models.UserpageRevision, error) {
+	revision, err := s.revisionRepository.FindByVersion(userpageID, version)
+	if err == mongo.ErrNoDocuments {
+		return nil, ErrRevisionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return revision, nil
+}
@@ -0,0 +1,258 @@
+package services
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"regexp"
+	"sane-discourse-backend/internal/models"
+	"sane-discourse-backend/internal/repositories"
+	"strings"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	MaxUserpages  = 50
+	MaxSlugLength = 60
+)
+
+var (
+	ErrDefaultUserpage  = errors.New("the default page can't be renamed or deleted")
+	ErrInvalidPageOrder = errors.New("the order must list each of your pages exactly once")
+	ErrTooManyUserpages = errors.New("you can't have more pages")
+	ErrNotUnlisted      = errors.New("only unlisted pages have a share link")
+)
+
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// UserpageSettings are the fields of a page other than its components.
+type UserpageSettings struct {
+	Slug       string
+	Title      string
+	Visibility models.UserpageVisibility
+}
+
+// GetUserpages lists the user's pages in their order, without components.
+// The default page is created if it doesn't exist yet.
+func (s *UserpageService) GetUserpages(userID primitive.ObjectID) ([]models.UserpageSummary, error) {
+	userpages, err := s.userpageRepository.FindAllByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(userpages) == 0 {
+		userpage, err := s.GetUserpage(userID, models.DefaultUserpageSlug)
+		if err != nil {
+			return nil, err
+		}
+		userpages = append(userpages, *userpage)
+	}
+	summaries := make([]models.UserpageSummary, len(userpages))
+	for i := range userpages {
+		summaries[i] = *userpages[i].ToSummary()
+	}
+	return summaries, nil
+}
+
+// CreateUserpage adds an empty page after the user's other pages.
+func (s *UserpageService) CreateUserpage(userID primitive.ObjectID, settings UserpageSettings) (*models.Userpage, error) {
+	if err := validateSettings(settings); err != nil {
+		return nil, err
+	}
+	if settings.Slug == models.DefaultUserpageSlug {
+		return nil, slugTaken()
+	}
+	userpages, err := s.userpageRepository.FindAllByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(userpages) >= MaxUserpages {
+		return nil, ErrTooManyUserpages
+	}
+	userpage := models.NewNamedUserpage([]models.Component{}, userID, settings.Slug, settings.Title, settings.Visibility)
+	if err = updateShareToken(userpage); err != nil {
+		return nil, err
+	}
+	for _, existing := range userpages {
+		if existing.Slug == settings.Slug {
+			return nil, slugTaken()
+		}
+		userpage.Position = max(userpage.Position, existing.Position+1)
+	}
+	created, err := s.userpageRepository.Create(*userpage)
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, slugTaken()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return created, nil
+}
+
+// UpdateUserpageSettings changes the slug, title and visibility of a page.
+// The slug of the default page can't be changed.
+func (s *UserpageService) UpdateUserpageSettings(userID primitive.ObjectID, slug string, version int, settings UserpageSettings) (*models.Userpage, error) {
+	if err := validateSettings(settings); err != nil {
+		return nil, err
+	}
+	return s.mutateSettings(userID, slug, version, func(userpage *models.Userpage) error {
+		if userpage.IsDefault() && settings.Slug != userpage.Slug {
+			return ErrDefaultUserpage
+		}
+		if settings.Slug != userpage.Slug {
+			_, err := s.userpageRepository.FindByUserIDAndSlug(userID, settings.Slug)
+			if err == nil {
+				return slugTaken()
+			}
+			if err != mongo.ErrNoDocuments {
+				return err
+			}
+		}
+		userpage.Slug = settings.Slug
+		userpage.Title = settings.Title
+		userpage.Visibility = settings.Visibility
+		return updateShareToken(userpage)
+	})
+}
+
+// RotateShareToken gives an unlisted page a new share token, so links
+// shared before stop working.
+func (s *UserpageService) RotateShareToken(userID primitive.ObjectID, slug string, version int) (*models.Userpage, error) {
+	return s.mutateSettings(userID, slug, version, func(userpage *models.Userpage) error {
+		if userpage.Visibility != models.UserpageVisibilityUnlisted {
+			return ErrNotUnlisted
+		}
+		token, err := newShareToken()
+		if err != nil {
+			return err
+		}
+		userpage.ShareToken = token
+		return nil
+	})
+}
+
+// DeleteUserpage deletes a page and its revisions, provided the page is
+// still at the given version. The default page can't be deleted.
+func (s *UserpageService) DeleteUserpage(userID primitive.ObjectID, slug string, version int) (*models.Userpage, error) {
+	userpage, err := s.findUserpage(userID, slug)
+	if err != nil {
+		return nil, err
+	}
+	if userpage.IsDefault() {
+		return nil, ErrDefaultUserpage
+	}
+	if userpage.Version != version {
+		return s.conflict(userpage)
+	}
+	err = s.userpageRepository.DeleteIfVersion(userpage.ID, version)
+	if errors.Is(err, repositories.ErrVersionConflict) {
+		current, err := s.userpageRepository.FindByID(userpage.ID)
+		if err == mongo.ErrNoDocuments {
+			return nil, ErrUserpageNotFound
+		}
+		if err != nil {
+			return nil, err
+		}
+		return s.conflict(current)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return nil, s.revisionRepository.DeleteByUserpageID(userpage.ID)
+}
+
+// ReorderUserpages puts the user's pages in the order of slugs, which must
+// name each page exactly once.
+func (s *UserpageService) ReorderUserpages(userID primitive.ObjectID, slugs []string) ([]models.UserpageSummary, error) {
+	userpages, err := s.userpageRepository.FindAllByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(slugs) != len(userpages) {
+		return nil, ErrInvalidPageOrder
+	}
+	idsBySlug := make(map[string]primitive.ObjectID, len(userpages))
+	for _, userpage := range userpages {
+		idsBySlug[userpage.Slug] = userpage.ID
+	}
+	ids := make([]primitive.ObjectID, len(slugs))
+	for i, slug := range slugs {
+		id, ok := idsBySlug[slug]
+		if !ok {
+			return nil, ErrInvalidPageOrder
+		}
+		// Each slug may only be used once
+		delete(idsBySlug, slug)
+		ids[i] = id
+	}
+	if err = s.userpageRepository.UpdatePositions(userID, ids); err != nil {
+		return nil, err
+	}
+	return s.GetUserpages(userID)
+}
+
+func validateSettings(settings UserpageSettings) error {
+	errs := fieldErrors{}
+	if !slugPattern.MatchString(settings.Slug) {
+		errs.add("slug", "must be lowercase letters and digits separated by single dashes")
+	}
+	validateLength("slug", settings.Slug, MaxSlugLength, &errs)
+	if strings.TrimSpace(settings.Title) == "" {
+		errs.add("title", "must not be empty")
+	}
+	validateLength("title", settings.Title, MaxTitleLength, &errs)
+	if !settings.Visibility.IsValid() {
+		errs.add("visibility", "must be %q, %q or %q",
+			models.UserpageVisibilityPublic, models.UserpageVisibilityUnlisted, models.UserpageVisibilityPrivate)
+	}
+	return errs.err()
+}
+
+func slugTaken() error {
+	return &ValidationError{Errors: []FieldError{{Field: "slug", Message: "you already have a page with this slug"}}}
+}
+
+// canView reports whether viewerID may see the page, given the share token
+// of the link they followed. viewerID is zero for anonymous viewers.
+func canView(userpage *models.Userpage, viewerID primitive.ObjectID, token string) bool {
+	if !viewerID.IsZero() && viewerID == userpage.UserID {
+		return true
+	}
+	switch userpage.Visibility {
+	case models.UserpageVisibilityPublic:
+		return true
+	case models.UserpageVisibilityUnlisted:
+		return token != "" && userpage.ShareToken != "" &&
+			subtle.ConstantTimeCompare([]byte(token), []byte(userpage.ShareToken)) == 1
+	default:
+		return false
+	}
+}
+
+// updateShareToken gives a page that became unlisted a share token, and
+// removes the token from a page that no longer is, which revokes its link.
+func updateShareToken(userpage *models.Userpage) error {
+	if userpage.Visibility != models.UserpageVisibilityUnlisted {
+		userpage.ShareToken = ""
+		return nil
+	}
+	if userpage.ShareToken != "" {
+		return nil
+	}
+	token, err := newShareToken()
+	if err != nil {
+		return err
+	}
+	userpage.ShareToken = token
+	return nil
+}
+
+func newShareToken() (string, error) {
+	token := make([]byte, 18)
+	if _, err := rand.Read(token); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(token), nil
+}
@@ -9,3 +9,19 @@ const (
 	PostTypePodcast PostType = "podcast"
 	PostTypePaper   PostType = "paper"
 )
+
+var PostTypes = []PostType{
+	PostTypeArticle,
+	PostTypeBlog,
+	PostTypeVideo,
+	PostTypePodcast,
+	PostTypePaper,
+}
+
+func (t PostType) IsValid() bool {
+	switch t {
+	case PostTypeArticle, PostTypeBlog, PostTypeVideo, PostTypePodcast, PostTypePaper:
+		return true
+	}
+	return false
+}
@@ -16,3 +16,87 @@ const (
 	ReactionTypeDownvote          ReactionType = "downvote"
 	ReactionTypeStrongDownvote    ReactionType = "strong_downvote"
 )
+
+// ReactionAxis is one independent dimension a user can react on.
+// A user holds a single signed ReactionValue per axis on a post.
+type ReactionAxis string
+
+const (
+	ReactionAxisAgreement  ReactionAxis = "agreement"
+	ReactionAxisImportance ReactionAxis = "importance"
+	ReactionAxisVote       ReactionAxis = "vote"
+)
+
+var ReactionAxes = []ReactionAxis{
+	ReactionAxisAgreement,
+	ReactionAxisImportance,
+	ReactionAxisVote,
+}
+
+func (a ReactionAxis) IsValid() bool {
+	switch a {
+	case ReactionAxisAgreement, ReactionAxisImportance, ReactionAxisVote:
+		return true
+	}
+	return false
+}
+
+// ReactionValue is the signed strength of a reaction on one axis:
+// -2 strong negative, -1 negative, 0 none, 1 positive, 2 strong positive.
+type ReactionValue int
+
+const (
+	ReactionValueStrongNegative ReactionValue = -2
+	ReactionValueNegative       ReactionValue = -1
+	ReactionValueNone           ReactionValue = 0
+	ReactionValuePositive       ReactionValue = 1
+	ReactionValueStrongPositive ReactionValue = 2
+)
+
+func (v ReactionValue) IsValid() bool {
+	return v >= ReactionValueStrongNegative && v <= ReactionValueStrongPositive
+}
+
+type reactionTypeInfo struct {
+	axis  ReactionAxis
+	value ReactionValue
+}
+
+var reactionTypeInfos = map[ReactionType]reactionTypeInfo{
+	ReactionTypeAgree:             {ReactionAxisAgreement, ReactionValuePositive},
+	ReactionTypeStrongAgree:       {ReactionAxisAgreement, ReactionValueStrongPositive},
+	ReactionTypeDisagree:          {ReactionAxisAgreement, ReactionValueNegative},
+	ReactionTypeStrongDisagree:    {ReactionAxisAgreement, ReactionValueStrongNegative},
+	ReactionTypeImportant:         {ReactionAxisImportance, ReactionValuePositive},
+	ReactionTypeStrongImportant:   {ReactionAxisImportance, ReactionValueStrongPositive},
+	ReactionTypeUnimportant:       {ReactionAxisImportance, ReactionValueNegative},
+	ReactionTypeStrongUnimportant: {ReactionAxisImportance, ReactionValueStrongNegative},
+	ReactionTypeUpvote:            {ReactionAxisVote, ReactionValuePositive},
+	ReactionTypeStrongUpvote:      {ReactionAxisVote, ReactionValueStrongPositive},
+	ReactionTypeDownvote:          {ReactionAxisVote, ReactionValueNegative},
+	ReactionTypeStrongDownvote:    {ReactionAxisVote, ReactionValueStrongNegative},
+}
+
+func (t ReactionType) IsValid() bool {
+	_, ok := reactionTypeInfos[t]
+	return ok
+}
+
+func (t ReactionType) Axis() ReactionAxis {
+	return reactionTypeInfos[t].axis
+}
+
+func (t ReactionType) Value() ReactionValue {
+	return reactionTypeInfos[t].value
+}
+
+// ReactionTypeFor returns the reaction type naming a value on an axis.
+// It returns false for ReactionValueNone and invalid combinations.
+func ReactionTypeFor(axis ReactionAxis, value ReactionValue) (ReactionType, bool) {
+	for reactionType, info := range reactionTypeInfos {
+		if info.axis == axis && info.value == value {
+			return reactionType, true
+		}
+	}
+	return "", false
+}
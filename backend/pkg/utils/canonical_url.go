@@ -0,0 +1,90 @@
+package utils
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+)
+
+var ErrInvalidURL = errors.New("invalid URL, only absolute http and https URLs are supported")
+
+// trackingParams are query parameters that only identify where a click
+// came from. Parameters starting with utm_ are removed as well.
+var trackingParams = map[string]bool{
+	"fbclid":  true,
+	"gclid":   true,
+	"dclid":   true,
+	"msclkid": true,
+	"yclid":   true,
+	"twclid":  true,
+	"igshid":  true,
+	"mc_cid":  true,
+	"mc_eid":  true,
+	"_hsenc":  true,
+	"_hsmi":   true,
+	"ref_src": true,
+}
+
+// hostPrefixes are subdomains that serve the same pages as the bare domain.
+var hostPrefixes = []string{"www.", "m.", "mobile."}
+
+// NormalizeURL returns the form of rawURL used to recognize the same page
+// behind different links. It upgrades http to https, lowercases the host
+// and drops www and mobile subdomains, default ports, fragments, tracking
+// parameters and trailing slashes, and sorts the remaining parameters.
+// The result is a key for comparing links, not necessarily a working link.
+func NormalizeURL(rawURL string) (string, error) {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return "", ErrInvalidURL
+	}
+	scheme := strings.ToLower(u.Scheme)
+	if (scheme != "http" && scheme != "https") || u.Hostname() == "" {
+		return "", ErrInvalidURL
+	}
+
+	host := strings.TrimSuffix(strings.ToLower(u.Hostname()), ".")
+	for _, prefix := range hostPrefixes {
+		if trimmed := strings.TrimPrefix(host, prefix); trimmed != host && strings.Contains(trimmed, ".") {
+			host = trimmed
+			break
+		}
+	}
+	if port := u.Port(); port != "" && port != "80" && port != "443" {
+		host += ":" + port
+	}
+
+	query := u.Query()
+	for key := range query {
+		if trackingParams[strings.ToLower(key)] || strings.HasPrefix(strings.ToLower(key), "utm_") {
+			query.Del(key)
+		}
+	}
+
+	path := strings.TrimRight(u.EscapedPath(), "/")
+	if path == "" {
+		path = "/"
+	}
+
+	normalized := "https://" + host + path
+	if encoded := query.Encode(); encoded != "" {
+		normalized += "?" + encoded
+	}
+	return normalized, nil
+}
+
+// sameSite reports whether two URLs are on the same host, ignoring the
+// subdomains NormalizeURL drops.
+func sameSite(a, b *url.URL) bool {
+	return siteHost(a) == siteHost(b)
+}
+
+func siteHost(u *url.URL) string {
+	host := strings.ToLower(u.Hostname())
+	for _, prefix := range hostPrefixes {
+		if trimmed := strings.TrimPrefix(host, prefix); trimmed != host {
+			return trimmed
+		}
+	}
+	return host
+}
@@ -0,0 +1,43 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeURL(t *testing.T) {
+	tests := map[string]string{
+		"https://example.com/post":                                   "https://example.com/post",
+		"http://example.com/post":                                    "https://example.com/post",
+		"https://example.com/post/":                                  "https://example.com/post",
+		"https://EXAMPLE.com/Post":                                   "https://example.com/Post",
+		"https://www.example.com/post":                               "https://example.com/post",
+		"https://m.example.com/post":                                 "https://example.com/post",
+		"https://mobile.example.com/post":                            "https://example.com/post",
+		"https://example.com:443/post":                               "https://example.com/post",
+		"http://example.com:80/post":                                 "https://example.com/post",
+		"https://example.com:8080/post":                              "https://example.com:8080/post",
+		"https://example.com/post#comments":                          "https://example.com/post",
+		"https://example.com/post?utm_source=x&utm_medium=y":         "https://example.com/post",
+		"https://example.com/post?fbclid=abc&id=3":                   "https://example.com/post?id=3",
+		"https://example.com/post?b=2&a=1&gclid=abc":                 "https://example.com/post?a=1&b=2",
+		"https://www.youtube.com/watch?v=zjkBMFhNj_g&utm_campaign=x": "https://youtube.com/watch?v=zjkBMFhNj_g",
+		"https://example.com":                                        "https://example.com/",
+		"https://example.com/":                                       "https://example.com/",
+		"https://m.com/post":                                         "https://m.com/post",
+		"https://example.com/a%20b/":                                 "https://example.com/a%20b",
+	}
+	for rawURL, expected := range tests {
+		normalized, err := NormalizeURL(rawURL)
+		assert.NoError(t, err, rawURL)
+		assert.Equal(t, expected, normalized, rawURL)
+	}
+}
+
+func TestNormalizeURLRejectsNonWebURLs(t *testing.T) {
+	for _, rawURL := range []string{"", "example.com/post", "/post", "ftp://example.com/file", "javascript:alert(1)", "https:///post"} {
+		_, err := NormalizeURL(rawURL)
+		assert.ErrorIs(t, err, ErrInvalidURL, rawURL)
+	}
+}
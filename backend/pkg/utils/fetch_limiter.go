@@ -0,0 +1,158 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrRateLimited = errors.New("too many requests to this site, try again later")
+
+const (
+	// maxActiveFetches caps the requests the scraper makes at once.
+	maxActiveFetches = 32
+	// maxActiveHostFetches caps the requests to one host at once.
+	maxActiveHostFetches = 2
+	// hostFetchInterval is the least time between starting two requests to
+	// the same host.
+	hostFetchInterval = 250 * time.Millisecond
+	// maxFetchWait is how long a request waits for its turn before failing
+	// with ErrRateLimited. It is shorter than fetchTimeout, so that there is
+	// time left for the request itself.
+	maxFetchWait = 5 * time.Second
+	// fetchPollInterval is how often a request waiting for a free slot
+	// checks again.
+	fetchPollInterval = 50 * time.Millisecond
+	// maxIdleHosts is how many hosts are remembered before the ones without
+	// requests in flight are forgotten.
+	maxIdleHosts = 1000
+)
+
+// fetchLimiter spaces out and caps the requests to each host, and caps the
+// requests overall. Requests wait for their turn for up to maxFetchWait.
+type fetchLimiter struct {
+	mu        sync.Mutex
+	active    int
+	maxActive int
+	hosts     map[string]*hostFetches
+
+	maxActiveHost int
+	interval      time.Duration
+}
+
+type hostFetches struct {
+	active int
+	// next is the earliest time the next request may start
+	next time.Time
+}
+
+func newFetchLimiter(maxActive, maxActiveHost int, interval time.Duration) *fetchLimiter {
+	return &fetchLimiter{
+		maxActive:     maxActive,
+		hosts:         map[string]*hostFetches{},
+		maxActiveHost: maxActiveHost,
+		interval:      interval,
+	}
+}
+
+// acquire waits until a request to host may start. The returned release
+// has to be called once the request is done.
+func (l *fetchLimiter) acquire(ctx context.Context, host string) (func(), error) {
+	ctx, cancel := context.WithTimeout(ctx, maxFetchWait)
+	defer cancel()
+	host = strings.ToLower(host)
+	for {
+		wait := l.tryAcquire(host)
+		if wait == 0 {
+			var once sync.Once
+			return func() { once.Do(func() { l.release(host) }) }, nil
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ErrRateLimited
+		case <-timer.C:
+		}
+	}
+}
+
+// tryAcquire takes a slot for host and returns 0, or returns how long to
+// wait before trying again.
+func (l *fetchLimiter) tryAcquire(host string) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	fetches, ok := l.hosts[host]
+	if !ok {
+		if len(l.hosts) >= maxIdleHosts {
+			l.forgetIdleHosts(now)
+		}
+		fetches = &hostFetches{}
+		l.hosts[host] = fetches
+	}
+	if now.Before(fetches.next) {
+		return fetches.next.Sub(now)
+	}
+	if fetches.active >= l.maxActiveHost || l.active >= l.maxActive {
+		return fetchPollInterval
+	}
+	fetches.active++
+	fetches.next = now.Add(l.interval)
+	l.active++
+	return 0
+}
+
+func (l *fetchLimiter) release(host string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.active--
+	if fetches, ok := l.hosts[host]; ok {
+		fetches.active--
+	}
+}
+
+func (l *fetchLimiter) forgetIdleHosts(now time.Time) {
+	for host, fetches := range l.hosts {
+		if fetches.active == 0 && !now.Before(fetches.next) {
+			delete(l.hosts, host)
+		}
+	}
+}
+
+// limitedTransport passes every request, including redirects, through a
+// fetchLimiter. A request holds its slot until its body is closed.
+type limitedTransport struct {
+	base    http.RoundTripper
+	limiter *fetchLimiter
+}
+
+func (t *limitedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	release, err := t.limiter.acquire(req.Context(), req.URL.Hostname())
+	if err != nil {
+		return nil, err
+	}
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	resp.Body = &releasingBody{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
+
+type releasingBody struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releasingBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+	return err
+}
@@ -0,0 +1,106 @@
+package utils
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func shortContext(t *testing.T) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+func TestFetchLimiterSpacesOutRequestsToAHost(t *testing.T) {
+	limiter := newFetchLimiter(10, 10, 200*time.Millisecond)
+
+	start := time.Now()
+	release, err := limiter.acquire(context.Background(), "example.com")
+	assert.NoError(t, err)
+	release()
+	// Other hosts don't have to wait
+	release, err = limiter.acquire(context.Background(), "example.org")
+	assert.NoError(t, err)
+	release()
+	assert.Less(t, time.Since(start), 100*time.Millisecond)
+
+	release, err = limiter.acquire(context.Background(), "EXAMPLE.com")
+	assert.NoError(t, err)
+	release()
+	assert.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond)
+}
+
+func TestFetchLimiterCapsRequestsToAHost(t *testing.T) {
+	limiter := newFetchLimiter(10, 1, 0)
+
+	release, err := limiter.acquire(context.Background(), "example.com")
+	assert.NoError(t, err)
+	_, err = limiter.acquire(shortContext(t), "example.com")
+	assert.ErrorIs(t, err, ErrRateLimited)
+	other, err := limiter.acquire(shortContext(t), "example.org")
+	assert.NoError(t, err)
+	other()
+
+	release()
+	// Releasing twice must not free a second slot
+	release()
+	release, err = limiter.acquire(shortContext(t), "example.com")
+	assert.NoError(t, err)
+	_, err = limiter.acquire(shortContext(t), "example.com")
+	assert.ErrorIs(t, err, ErrRateLimited)
+	release()
+}
+
+func TestFetchLimiterCapsAllRequests(t *testing.T) {
+	limiter := newFetchLimiter(1, 1, 0)
+
+	release, err := limiter.acquire(context.Background(), "example.com")
+	assert.NoError(t, err)
+	_, err = limiter.acquire(shortContext(t), "example.org")
+	assert.ErrorIs(t, err, ErrRateLimited)
+	release()
+	release, err = limiter.acquire(shortContext(t), "example.org")
+	assert.NoError(t, err)
+	release()
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func TestLimitedTransportHoldsSlotUntilBodyIsClosed(t *testing.T) {
+	transport := &limitedTransport{
+		base: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("ok"))}, nil
+		}),
+		limiter: newFetchLimiter(10, 1, 0),
+	}
+	newRequest := func(ctx context.Context) *http.Request {
+		req, err := http.NewRequestWithContext(ctx, "GET", "https://example.com/", nil)
+		if err != nil {
+			t.Fatalf("Failed to create request: %v", err)
+		}
+		return req
+	}
+
+	resp, err := transport.RoundTrip(newRequest(context.Background()))
+	if err != nil {
+		t.Fatalf("Failed to make request: %v", err)
+	}
+	_, err = transport.RoundTrip(newRequest(shortContext(t)))
+	assert.ErrorIs(t, err, ErrRateLimited)
+
+	resp.Body.Close()
+	resp, err = transport.RoundTrip(newRequest(shortContext(t)))
+	if assert.NoError(t, err) {
+		resp.Body.Close()
+	}
+}
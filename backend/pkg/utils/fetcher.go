@@ -0,0 +1,205 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"slices"
+	"syscall"
+	"time"
+)
+
+var (
+	ErrBlockedAddress         = errors.New("fetching private, loopback and link-local addresses is not allowed")
+	ErrTooManyRedirects       = errors.New("too many redirects")
+	ErrResponseTooLarge       = errors.New("response is too large")
+	ErrUnsupportedContentType = errors.New("unsupported content type")
+)
+
+const (
+	fetchTimeout = 10 * time.Second
+	maxRedirects = 5
+)
+
+// blockedPrefixes are ranges that aren't caught by the netip.Addr checks
+// in isPublicAddress but still don't lead to the public internet.
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),      // "this" network
+	netip.MustParsePrefix("100.64.0.0/10"),  // carrier-grade NAT, also cloud metadata on some providers
+	netip.MustParsePrefix("192.0.0.0/24"),   // IETF protocol assignments
+	netip.MustParsePrefix("198.18.0.0/15"),  // benchmarking
+	netip.MustParsePrefix("240.0.0.0/4"),    // reserved
+	netip.MustParsePrefix("64:ff9b::/96"),   // NAT64, can reach private IPv4 addresses
+	netip.MustParsePrefix("64:ff9b:1::/48"), // local-use NAT64
+	netip.MustParsePrefix("2002::/16"),      // 6to4, embeds an IPv4 address
+	netip.MustParsePrefix("fec0::/10"),      // deprecated site-local
+	netip.MustParsePrefix("2001:db8::/32"),  // documentation
+	netip.MustParsePrefix("2001::/32"),      // Teredo, embeds an IPv4 address
+}
+
+// isPublicAddress reports whether addr is on the public internet.
+func isPublicAddress(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if !addr.IsGlobalUnicast() || addr.IsPrivate() || addr.IsLoopback() ||
+		addr.IsLinkLocalUnicast() || addr.IsUnspecified() || addr.IsMulticast() {
+		return false
+	}
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+// FetchResponse is a response read in full by Fetcher.
+type FetchResponse struct {
+	// URL is where the response came from after following redirects.
+	URL         *url.URL
+	ContentType string
+	Body        []byte
+}
+
+// Fetcher GETs user supplied URLs. It only connects to public addresses,
+// checked on every connection after DNS resolution, so neither redirects
+// nor DNS answers can point it at the server's own network.
+type Fetcher struct {
+	client *http.Client
+}
+
+// NewFetcher returns a Fetcher whose requests are rate limited per host,
+// see fetchLimiter.
+func NewFetcher() *Fetcher {
+	return newFetcher(
+		func(addrPort netip.AddrPort) bool {
+			return isPublicAddress(addrPort.Addr())
+		},
+		newFetchLimiter(maxActiveFetches, maxActiveHostFetches, hostFetchInterval),
+	)
+}
+
+// newFetcher returns a Fetcher that only connects to addresses allowed by
+// allowAddress, which tests use to reach local servers.
+func newFetcher(allowAddress func(netip.AddrPort) bool, limiter *fetchLimiter) *Fetcher {
+	dialer := &net.Dialer{
+		Timeout: fetchTimeout,
+		// Control runs with the resolved address of each connection
+		Control: func(network, address string, _ syscall.RawConn) error {
+			addrPort, err := netip.ParseAddrPort(address)
+			if err != nil || !allowAddress(addrPort) {
+				return ErrBlockedAddress
+			}
+			return nil
+		},
+	}
+	transport := &http.Transport{
+		// Proxies would connect on our behalf, skipping the address check
+		Proxy:                  nil,
+		DialContext:            dialer.DialContext,
+		TLSHandshakeTimeout:    fetchTimeout,
+		ResponseHeaderTimeout:  fetchTimeout,
+		MaxResponseHeaderBytes: 64 << 10,
+		MaxIdleConns:           100,
+		IdleConnTimeout:        90 * time.Second,
+	}
+	return &Fetcher{
+		client: &http.Client{
+			Transport: &limitedTransport{base: transport, limiter: limiter},
+			Timeout:   fetchTimeout,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if len(via) > maxRedirects {
+					return ErrTooManyRedirects
+				}
+				return checkScheme(req.URL)
+			},
+		},
+	}
+}
+
+// Fetch GETs rawURL and reads at most maxSize bytes of the body. The
+// response must be successful and have one of the contentTypes.
+func (f *Fetcher) Fetch(rawURL string, maxSize int64, contentTypes []string) (*FetchResponse, error) {
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, ErrInvalidURL
+	}
+	if err = checkScheme(parsedURL); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest("GET", parsedURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; SaneDiscourse/1.0)")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		// The wrapped errors name the resolved address, which must not be
+		// shown for internal hosts
+		for _, known := range []error{ErrBlockedAddress, ErrTooManyRedirects, ErrInvalidURL, ErrRateLimited} {
+			if errors.Is(err, known) {
+				return nil, known
+			}
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("request failed with status %d", resp.StatusCode)
+	}
+	if resp.ContentLength > maxSize {
+		return nil, ErrResponseTooLarge
+	}
+	// The type is checked before reading if the server sends it, and
+	// sniffed from the body otherwise
+	contentType := resp.Header.Get("Content-Type")
+	if contentType != "" {
+		if _, err = checkContentType(contentType, contentTypes); err != nil {
+			return nil, err
+		}
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > maxSize {
+		return nil, ErrResponseTooLarge
+	}
+	if contentType == "" {
+		contentType = http.DetectContentType(body)
+	}
+	mediaType, err := checkContentType(contentType, contentTypes)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FetchResponse{
+		URL:         resp.Request.URL,
+		ContentType: mediaType,
+		Body:        body,
+	}, nil
+}
+
+func checkScheme(u *url.URL) error {
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return ErrInvalidURL
+	}
+	return nil
+}
+
+// checkContentType returns the media type of a Content-Type header if it
+// is one of contentTypes.
+func checkContentType(contentType string, contentTypes []string) (string, error) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil || !slices.Contains(contentTypes, mediaType) {
+		return "", fmt.Errorf("%w %q", ErrUnsupportedContentType, contentType)
+	}
+	return mediaType, nil
+}
@@ -0,0 +1,211 @@
+package utils
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// unlimited lets tests make requests as fast as they like.
+func unlimited() *fetchLimiter {
+	return newFetchLimiter(maxActiveFetches, maxActiveFetches, 0)
+}
+
+// localFetcher is allowed to connect to the test servers on localhost.
+func localFetcher() *Fetcher {
+	return newFetcher(func(addrPort netip.AddrPort) bool {
+		return addrPort.Addr().IsLoopback()
+	}, unlimited())
+}
+
+func serverPort(t *testing.T, server *httptest.Server) uint16 {
+	t.Helper()
+	u, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse server URL: %v", err)
+	}
+	port, err := strconv.Atoi(u.Port())
+	if err != nil {
+		t.Fatalf("Failed to parse server port: %v", err)
+	}
+	return uint16(port)
+}
+
+func TestIsPublicAddress(t *testing.T) {
+	tests := map[string]bool{
+		"93.184.216.34":    true,
+		"8.8.8.8":          true,
+		"2606:4700::1111":  true,
+		"127.0.0.1":        false,
+		"10.0.0.1":         false,
+		"172.16.0.1":       false,
+		"192.168.1.1":      false,
+		"169.254.169.254":  false,
+		"100.100.100.200":  false,
+		"0.0.0.0":          false,
+		"0.1.2.3":          false,
+		"224.0.0.1":        false,
+		"255.255.255.255":  false,
+		"::1":              false,
+		"::":               false,
+		"fe80::1":          false,
+		"fd00:ec2::254":    false,
+		"::ffff:127.0.0.1": false,
+		"::ffff:10.0.0.1":  false,
+		"64:ff9b::a00:1":   false,
+		"2002:a00:1::":     false,
+	}
+	for address, expected := range tests {
+		assert.Equal(t, expected, isPublicAddress(netip.MustParseAddr(address)), address)
+	}
+}
+
+func TestFetchBlocksLocalAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "<html></html>")
+	}))
+	defer server.Close()
+
+	_, err := NewFetcher().Fetch(server.URL, maxPageSize, htmlContentTypes)
+	assert.ErrorIs(t, err, ErrBlockedAddress)
+	_, err = NewFetcher().Fetch(strings.Replace(server.URL, "127.0.0.1", "localhost", 1), maxPageSize, htmlContentTypes)
+	assert.ErrorIs(t, err, ErrBlockedAddress)
+}
+
+func TestFetchChecksEveryRedirect(t *testing.T) {
+	internal := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "<html><title>secret</title></html>")
+	}))
+	defer internal.Close()
+	public := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, internal.URL, http.StatusFound)
+	}))
+	defer public.Close()
+
+	// Only the redirecting server counts as public
+	publicPort := serverPort(t, public)
+	fetcher := newFetcher(func(addrPort netip.AddrPort) bool {
+		return addrPort.Port() == publicPort
+	}, unlimited())
+	_, err := fetcher.Fetch(public.URL, maxPageSize, htmlContentTypes)
+	assert.ErrorIs(t, err, ErrBlockedAddress)
+}
+
+func TestFetchLimitsRedirects(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hops, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/"))
+		if hops > 0 {
+			http.Redirect(w, r, "/"+strconv.Itoa(hops-1), http.StatusFound)
+			return
+		}
+		fmt.Fprint(w, "<html></html>")
+	}))
+	defer server.Close()
+
+	resp, err := localFetcher().Fetch(server.URL+"/"+strconv.Itoa(maxRedirects), maxPageSize, htmlContentTypes)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "/0", resp.URL.Path)
+	}
+	_, err = localFetcher().Fetch(server.URL+"/"+strconv.Itoa(maxRedirects+1), maxPageSize, htmlContentTypes)
+	assert.ErrorIs(t, err, ErrTooManyRedirects)
+}
+
+func TestFetchOnlyAllowsWebURLs(t *testing.T) {
+	for _, rawURL := range []string{"file:///etc/passwd", "ftp://example.com/file", "gopher://127.0.0.1:27017/", "/relative"} {
+		_, err := localFetcher().Fetch(rawURL, maxPageSize, htmlContentTypes)
+		assert.ErrorIs(t, err, ErrInvalidURL, rawURL)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "file:///etc/passwd", http.StatusFound)
+	}))
+	defer server.Close()
+	_, err := localFetcher().Fetch(server.URL, maxPageSize, htmlContentTypes)
+	assert.ErrorIs(t, err, ErrInvalidURL)
+}
+
+func TestFetchLimitsBodySize(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		size, _ := strconv.Atoi(r.URL.Query().Get("size"))
+		if r.URL.Query().Get("chunked") == "" {
+			w.Header().Set("Content-Length", strconv.Itoa(size))
+		}
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte(strings.Repeat("a", size)))
+	}))
+	defer server.Close()
+
+	_, err := localFetcher().Fetch(server.URL+"?size=100", 100, htmlContentTypes)
+	assert.NoError(t, err)
+	_, err = localFetcher().Fetch(server.URL+"?size=101", 100, htmlContentTypes)
+	assert.ErrorIs(t, err, ErrResponseTooLarge)
+	_, err = localFetcher().Fetch(server.URL+"?size=5000&chunked=1", 100, htmlContentTypes)
+	assert.ErrorIs(t, err, ErrResponseTooLarge)
+}
+
+func TestFetchChecksContentType(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/html":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			fmt.Fprint(w, "<html></html>")
+		case "/binary":
+			w.Header().Set("Content-Type", "application/octet-stream")
+			fmt.Fprint(w, "<html></html>")
+		case "/untyped":
+			// Sent without a Content-Type, which is sniffed
+			w.Header()["Content-Type"] = nil
+			fmt.Fprint(w, "<!DOCTYPE html><html></html>")
+		case "/missing":
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	resp, err := localFetcher().Fetch(server.URL+"/html", maxPageSize, htmlContentTypes)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "text/html", resp.ContentType)
+	}
+	_, err = localFetcher().Fetch(server.URL+"/binary", maxPageSize, htmlContentTypes)
+	assert.ErrorIs(t, err, ErrUnsupportedContentType)
+	_, err = localFetcher().Fetch(server.URL+"/html", maxOEmbedSize, oembedContentTypes)
+	assert.ErrorIs(t, err, ErrUnsupportedContentType)
+	_, err = localFetcher().Fetch(server.URL+"/untyped", maxPageSize, htmlContentTypes)
+	assert.NoError(t, err)
+	_, err = localFetcher().Fetch(server.URL+"/missing", maxPageSize, htmlContentTypes)
+	assert.Error(t, err)
+}
+
+func TestScrapeMetadataFollowsRedirects(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/old":
+			http.Redirect(w, r, "/talk?utm_source=feed", http.StatusMovedPermanently)
+		case "/talk":
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprintf(w, `<html><head><meta property="og:title" content="A Talk">
+<link rel="alternate" type="application/json+oembed" href="%s/oembed"></head></html>`, server.URL)
+		case "/oembed":
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"type": "video", "author_name": "Carol Speaker"}`)
+		}
+	}))
+	defer server.Close()
+
+	metadata, err := scrapeMetadata(localFetcher(), server.URL+"/old")
+	if err != nil {
+		t.Fatalf("Failed to scrape metadata: %v", err)
+	}
+	assert.Equal(t, server.URL+"/talk?utm_source=feed", metadata.URL)
+	assert.Equal(t, strings.Replace(server.URL, "http://", "https://", 1)+"/talk", metadata.CanonicalURL)
+	assert.Equal(t, "A Talk", metadata.Title)
+	assert.Equal(t, "Carol Speaker", metadata.Author)
+}
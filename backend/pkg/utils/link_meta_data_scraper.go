@@ -1,146 +1,77 @@
 package utils
 
 import (
-	"net/http"
+	"bytes"
+	"sane-discourse-backend/pkg/types"
 	"strings"
-	"time"
-
-	"golang.org/x/net/html"
 )
 
 type LinkMetadata struct {
-	Title       string `json:"title"`
-	Description string `json:"description"`
-	ImageURL    string `json:"image_url"`
-	SiteName    string `json:"site_name"`
-	Author      string `json:"author"`
-	URL         string `json:"url"`
-	Type        string `json:"type"`
+	Title       string         `json:"title"`
+	Description string         `json:"description"`
+	ImageURL    string         `json:"image_url"`
+	SiteName    string         `json:"site_name"`
+	Author      string         `json:"author"`
+	URL         string         `json:"url"`
+	Type        types.PostType `json:"type"`
+	// CanonicalURL identifies the page, see NormalizeURL.
+	CanonicalURL string `json:"canonical_url"`
 }
 
-func ScrapeMetadata(url string) (*LinkMetadata, error) {
-	client := &http.Client{
-		Timeout: 10 * time.Second,
-	}
+const (
+	// maxPageSize limits fetched pages. Metadata is in the head, so this
+	// only has to fit the odd page with a lot of inline scripts.
+	maxPageSize = 5 << 20
+	// maxOEmbedSize limits oEmbed responses, which are small JSON documents.
+	maxOEmbedSize = 1 << 20
+)
 
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return nil, err
-	}
+var (
+	htmlContentTypes   = []string{"text/html", "application/xhtml+xml"}
+	oembedContentTypes = []string{"application/json", "text/json", "application/json+oembed"}
+)
 
-	req.Header.Set("User-Agent", "Mozilla/5.0 (compatible; SaneDiscourse/1.0)")
+var defaultFetcher = NewFetcher()
 
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
+// ScrapeMetadata fetches the page at url and extracts its metadata. Only
+// public http and https URLs can be fetched, see Fetcher.
+func ScrapeMetadata(url string) (*LinkMetadata, error) {
+	return scrapeMetadata(defaultFetcher, url)
+}
 
-	doc, err := html.Parse(resp.Body)
+func scrapeMetadata(fetcher *Fetcher, url string) (*LinkMetadata, error) {
+	page, err := fetcher.Fetch(url, maxPageSize, htmlContentTypes)
 	if err != nil {
 		return nil, err
 	}
 
-	// Initialize metadata with empty strings for all fields
-	metadata := &LinkMetadata{
-		Title:       "",
-		Description: "",
-		ImageURL:    "",
-		SiteName:    "",
-		Author:      "",
-		URL:         url,
-		Type:        "",
-	}
-
-	var parseNode func(*html.Node)
-	parseNode = func(n *html.Node) {
-		if n.Type == html.ElementNode {
-			switch n.Data {
-			case "meta":
-				extractMetaTag(n, metadata)
-			case "title":
-				if n.FirstChild != nil && metadata.Title == "" {
-					metadata.Title = strings.TrimSpace(n.FirstChild.Data)
-				}
-			}
+	pipeline := NewMetadataPipeline(func(oembedURL string) ([]byte, error) {
+		resp, err := fetcher.Fetch(oembedURL, maxOEmbedSize, oembedContentTypes)
+		if err != nil {
+			return nil, err
 		}
-
-		for c := n.FirstChild; c != nil; c = c.NextSibling {
-			parseNode(c)
-		}
-	}
-
-	parseNode(doc)
-
-	// Ensure all fields have empty strings if not found (already done in initialization)
-	return metadata, nil
+		return resp.Body, nil
+	})
+	// Metadata is about the page redirects ended up at
+	return pipeline.Extract(page.URL.String(), bytes.NewReader(page.Body))
 }
 
-func extractMetaTag(n *html.Node, metadata *LinkMetadata) {
-	var property, name, content string
-
-	for _, attr := range n.Attr {
-		switch attr.Key {
-		case "property":
-			property = attr.Val
-		case "name":
-			name = attr.Val
-		case "content":
-			content = attr.Val
-		}
-	}
-
-	switch property {
-	case "og:title":
-		if metadata.Title == "" {
-			metadata.Title = content
-		}
-	case "og:description":
-		if metadata.Description == "" {
-			metadata.Description = content
-		}
-	case "og:image":
-		if metadata.ImageURL == "" {
-			metadata.ImageURL = content
-		}
-	case "og:site_name":
-		if metadata.SiteName == "" {
-			metadata.SiteName = content
-		}
-	case "og:type":
-		if metadata.Type == "" {
-			metadata.Type = content
-		}
-	case "article:author":
-		if metadata.Author == "" {
-			metadata.Author = content
-		}
-	}
-
-	switch name {
-	case "twitter:title":
-		if metadata.Title == "" {
-			metadata.Title = content
-		}
-	case "twitter:description":
-		if metadata.Description == "" {
-			metadata.Description = content
-		}
-	case "twitter:image":
-		if metadata.ImageURL == "" {
-			metadata.ImageURL = content
-		}
-	case "twitter:creator":
-		if metadata.Author == "" {
-			metadata.Author = strings.TrimPrefix(content, "@")
-		}
-	case "description":
-		if metadata.Description == "" {
-			metadata.Description = content
-		}
-	case "author":
-		if metadata.Author == "" {
-			metadata.Author = content
-		}
+// MetaTagExtractor reads OpenGraph, Twitter and basic meta tags.
+type MetaTagExtractor struct{}
+
+func (MetaTagExtractor) Extract(page *ScrapedPage, metadata *LinkMetadata) {
+	setIfEmpty(&metadata.Title, page.MetaContent("og:title"))
+	setIfEmpty(&metadata.Title, page.MetaContent("twitter:title"))
+	setIfEmpty(&metadata.Description, page.MetaContent("og:description"))
+	setIfEmpty(&metadata.Description, page.MetaContent("twitter:description"))
+	setIfEmpty(&metadata.Description, page.MetaContent("description"))
+	setIfEmpty(&metadata.ImageURL, page.MetaContent("og:image"))
+	setIfEmpty(&metadata.ImageURL, page.MetaContent("twitter:image"))
+	setIfEmpty(&metadata.SiteName, page.MetaContent("og:site_name"))
+	// article:author is often a link to a profile rather than a name
+	if author := page.MetaContent("article:author"); !strings.HasPrefix(author, "http") {
+		setIfEmpty(&metadata.Author, author)
 	}
+	setIfEmpty(&metadata.Author, page.MetaContent("author"))
+	setIfEmpty(&metadata.Author, strings.TrimPrefix(page.MetaContent("twitter:creator"), "@"))
 }
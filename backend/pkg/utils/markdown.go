@@ -0,0 +1,317 @@
+package utils
+
+import (
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// The Markdown dialect of paragraphs is deliberately small: emphasis, strong
+// emphasis, inline code, links, lists and blockquotes. Everything else,
+// including raw HTML, is shown as text. The HTML is built only from the
+// tags below with all text escaped, so it is safe by construction.
+
+// maxQuoteDepth limits nested blockquotes, deeper ones are shown as text.
+const maxQuoteDepth = 4
+
+var (
+	unorderedItem = regexp.MustCompile(`^ {0,3}[-*+] +`)
+	orderedItem   = regexp.MustCompile(`^ {0,3}[0-9]{1,9}[.)] +`)
+	quoteLine     = regexp.MustCompile(`^ {0,3}> ?`)
+)
+
+type markdownNodeKind int
+
+const (
+	textNode markdownNodeKind = iota
+	codeNode
+	emphasisNode
+	strongNode
+	linkNode
+	paragraphNode
+	unorderedListNode
+	orderedListNode
+	listItemNode
+	blockquoteNode
+)
+
+type markdownNode struct {
+	kind     markdownNodeKind
+	text     string // text and code nodes
+	url      string // link nodes
+	children []markdownNode
+}
+
+// RenderMarkdown renders paragraph Markdown to sanitized HTML. Links whose
+// target is not a safe URL are rendered as their text only.
+func RenderMarkdown(source string) string {
+	var b strings.Builder
+	for _, block := range parseBlocks(normalizeNewlines(source), 0) {
+		renderNode(&b, block)
+	}
+	return b.String()
+}
+
+// MarkdownLinkTargets returns the targets of all links in source, in order.
+func MarkdownLinkTargets(source string) []string {
+	targets := []string{}
+	var walk func(nodes []markdownNode)
+	walk = func(nodes []markdownNode) {
+		for _, node := range nodes {
+			if node.kind == linkNode {
+				targets = append(targets, node.url)
+			}
+			walk(node.children)
+		}
+	}
+	walk(parseBlocks(normalizeNewlines(source), 0))
+	return targets
+}
+
+// IsSafeLinkURL reports whether a link target may be rendered as a link:
+// absolute http and https URLs, and mailto. This rules out javascript:,
+// data: and similar schemes, as well as relative URLs.
+func IsSafeLinkURL(rawURL string) bool {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return false
+	}
+	switch strings.ToLower(parsed.Scheme) {
+	case "http", "https":
+		return parsed.Host != ""
+	case "mailto":
+		return parsed.Opaque != ""
+	default:
+		return false
+	}
+}
+
+func normalizeNewlines(source string) string {
+	source = strings.ReplaceAll(source, "\r\n", "\n")
+	return strings.ReplaceAll(source, "\r", "\n")
+}
+
+func parseBlocks(source string, depth int) []markdownNode {
+	lines := strings.Split(source, "\n")
+	blocks := []markdownNode{}
+	paragraph := []string{}
+	flushParagraph := func() {
+		if len(paragraph) > 0 {
+			blocks = append(blocks, markdownNode{
+				kind:     paragraphNode,
+				children: parseInline(strings.Join(paragraph, "\n"), true),
+			})
+			paragraph = paragraph[:0]
+		}
+	}
+
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		switch {
+		case strings.TrimSpace(line) == "":
+			flushParagraph()
+			i++
+		case quoteLine.MatchString(line) && depth < maxQuoteDepth:
+			flushParagraph()
+			quoted := []string{}
+			for i < len(lines) && quoteLine.MatchString(lines[i]) {
+				quoted = append(quoted, quoteLine.ReplaceAllString(lines[i], ""))
+				i++
+			}
+			blocks = append(blocks, markdownNode{
+				kind:     blockquoteNode,
+				children: parseBlocks(strings.Join(quoted, "\n"), depth+1),
+			})
+		case unorderedItem.MatchString(line):
+			flushParagraph()
+			var list markdownNode
+			list, i = parseList(lines, i, unorderedListNode, unorderedItem)
+			blocks = append(blocks, list)
+		case orderedItem.MatchString(line):
+			flushParagraph()
+			var list markdownNode
+			list, i = parseList(lines, i, orderedListNode, orderedItem)
+			blocks = append(blocks, list)
+		default:
+			paragraph = append(paragraph, strings.TrimSpace(line))
+			i++
+		}
+	}
+	flushParagraph()
+	return blocks
+}
+
+// parseList reads consecutive items of one list starting at lines[start].
+// Indented lines continue the previous item. Lists don't nest.
+func parseList(lines []string, start int, kind markdownNodeKind, marker *regexp.Regexp) (markdownNode, int) {
+	list := markdownNode{kind: kind}
+	items := []string{}
+	i := start
+	for i < len(lines) {
+		line := lines[i]
+		if marker.MatchString(line) {
+			items = append(items, marker.ReplaceAllString(line, ""))
+		} else if strings.TrimSpace(line) != "" && strings.HasPrefix(line, "  ") {
+			items[len(items)-1] += "\n" + strings.TrimSpace(line)
+		} else {
+			break
+		}
+		i++
+	}
+	for _, item := range items {
+		list.children = append(list.children, markdownNode{
+			kind:     listItemNode,
+			children: parseInline(item, true),
+		})
+	}
+	return list, i
+}
+
+// parseInline parses emphasis, code spans and links. Links are not allowed
+// inside link text.
+func parseInline(source string, allowLinks bool) []markdownNode {
+	nodes := []markdownNode{}
+	var text strings.Builder
+	flushText := func() {
+		if text.Len() > 0 {
+			nodes = append(nodes, markdownNode{kind: textNode, text: text.String()})
+			text.Reset()
+		}
+	}
+
+	for i := 0; i < len(source); {
+		c := source[i]
+		switch {
+		case c == '\\' && i+1 < len(source) && isMarkdownPunct(source[i+1]):
+			text.WriteByte(source[i+1])
+			i += 2
+			continue
+		case c == '`':
+			if end := strings.IndexByte(source[i+1:], '`'); end > 0 {
+				flushText()
+				nodes = append(nodes, markdownNode{kind: codeNode, text: source[i+1 : i+1+end]})
+				i += end + 2
+				continue
+			}
+		case c == '[' && allowLinks:
+			if label, target, length, ok := parseLink(source[i:]); ok {
+				flushText()
+				nodes = append(nodes, markdownNode{
+					kind:     linkNode,
+					url:      target,
+					children: parseInline(label, false),
+				})
+				i += length
+				continue
+			}
+		case c == '*' || (c == '_' && (i == 0 || !isWordByte(source[i-1]))):
+			marker := string(c)
+			kind := emphasisNode
+			if strings.HasPrefix(source[i:], marker+marker) {
+				marker += marker
+				kind = strongNode
+			}
+			inner, ok := delimited(source[i:], marker)
+			// Underscores inside words, as in snake_case, are not emphasis
+			if end := i + len(inner) + 2*len(marker); ok && c == '_' && end < len(source) && isWordByte(source[end]) {
+				ok = false
+			}
+			if ok {
+				flushText()
+				nodes = append(nodes, markdownNode{
+					kind:     kind,
+					children: parseInline(inner, allowLinks),
+				})
+				i += len(inner) + 2*len(marker)
+				continue
+			}
+		}
+		text.WriteByte(c)
+		i++
+	}
+	flushText()
+	return nodes
+}
+
+// delimited returns the text between marker at the start of source and the
+// next marker. The text must not start or end with a space.
+func delimited(source string, marker string) (string, bool) {
+	rest := source[len(marker):]
+	end := strings.Index(rest, marker)
+	if end <= 0 {
+		return "", false
+	}
+	inner := rest[:end]
+	if strings.TrimSpace(inner) != inner {
+		return "", false
+	}
+	return inner, true
+}
+
+// parseLink parses "[label](target)" at the start of source and returns its
+// parts and total length.
+func parseLink(source string) (string, string, int, bool) {
+	labelEnd := strings.IndexByte(source, ']')
+	if labelEnd < 1 || labelEnd+1 >= len(source) || source[labelEnd+1] != '(' {
+		return "", "", 0, false
+	}
+	targetEnd := strings.IndexByte(source[labelEnd+2:], ')')
+	if targetEnd < 0 {
+		return "", "", 0, false
+	}
+	label := source[1:labelEnd]
+	target := strings.TrimSpace(source[labelEnd+2 : labelEnd+2+targetEnd])
+	return label, target, labelEnd + 2 + targetEnd + 1, true
+}
+
+func isWordByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+func isMarkdownPunct(c byte) bool {
+	return strings.IndexByte("\\`*_[]()>#+-.!", c) >= 0
+}
+
+func renderNode(b *strings.Builder, node markdownNode) {
+	switch node.kind {
+	case textNode:
+		b.WriteString(html.EscapeString(node.text))
+	case codeNode:
+		b.WriteString("<code>" + html.EscapeString(node.text) + "</code>")
+	case emphasisNode:
+		renderChildren(b, "em", node.children)
+	case strongNode:
+		renderChildren(b, "strong", node.children)
+	case linkNode:
+		if !IsSafeLinkURL(node.url) {
+			for _, child := range node.children {
+				renderNode(b, child)
+			}
+			return
+		}
+		b.WriteString(`<a href="` + html.EscapeString(node.url) + `" rel="nofollow noopener noreferrer">`)
+		for _, child := range node.children {
+			renderNode(b, child)
+		}
+		b.WriteString("</a>")
+	case paragraphNode:
+		renderChildren(b, "p", node.children)
+	case unorderedListNode:
+		renderChildren(b, "ul", node.children)
+	case orderedListNode:
+		renderChildren(b, "ol", node.children)
+	case listItemNode:
+		renderChildren(b, "li", node.children)
+	case blockquoteNode:
+		renderChildren(b, "blockquote", node.children)
+	}
+}
+
+func renderChildren(b *strings.Builder, tag string, children []markdownNode) {
+	b.WriteString("<" + tag + ">")
+	for _, child := range children {
+		renderNode(b, child)
+	}
+	b.WriteString("</" + tag + ">")
+}
@@ -0,0 +1,94 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderMarkdown(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{"plain text", "Hello world", "<p>Hello world</p>"},
+		{"paragraphs", "One\n\nTwo", "<p>One</p><p>Two</p>"},
+		{"emphasis", "*a* and _b_", "<p><em>a</em> and <em>b</em></p>"},
+		{"strong", "**a** and __b__", "<p><strong>a</strong> and <strong>b</strong></p>"},
+		{"snake case", "snake_case_name", "<p>snake_case_name</p>"},
+		{"unclosed emphasis", "2 * 3 = 6", "<p>2 * 3 = 6</p>"},
+		{"inline code", "run `go test ./...`", "<p>run <code>go test ./...</code></p>"},
+		{"code is literal", "`*not em*`", "<p><code>*not em*</code></p>"},
+		{"escaped marker", `\*not em\*`, "<p>*not em*</p>"},
+		{
+			"link",
+			"see [the docs](https://go.dev/doc)",
+			`<p>see <a href="https://go.dev/doc" rel="nofollow noopener noreferrer">the docs</a></p>`,
+		},
+		{
+			"link with emphasis",
+			"[*Go*](https://go.dev)",
+			`<p><a href="https://go.dev" rel="nofollow noopener noreferrer"><em>Go</em></a></p>`,
+		},
+		{"unordered list", "- a\n- b", "<ul><li>a</li><li>b</li></ul>"},
+		{"ordered list", "1. a\n2. b", "<ol><li>a</li><li>b</li></ol>"},
+		{"list item continuation", "- a\n  b\n- c", "<ul><li>a\nb</li><li>c</li></ul>"},
+		{"blockquote", "> quoted\n> *text*", "<blockquote><p>quoted\n<em>text</em></p></blockquote>"},
+		{"nested blockquote", "> > deep", "<blockquote><blockquote><p>deep</p></blockquote></blockquote>"},
+		{"heading is text", "# Title", "<p># Title</p>"},
+		{"windows newlines", "One\r\n\r\nTwo", "<p>One</p><p>Two</p>"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, RenderMarkdown(test.source))
+		})
+	}
+}
+
+func TestRenderMarkdownSanitizes(t *testing.T) {
+	tests := []struct {
+		name     string
+		source   string
+		expected string
+	}{
+		{"raw html", "<script>alert(1)</script>", "<p>&lt;script&gt;alert(1)&lt;/script&gt;</p>"},
+		{"javascript link", "[click](javascript:alert(1))", "<p>click)</p>"},
+		{"uppercase javascript link", "[click](JavaScript:alert)", "<p>click</p>"},
+		{"data link", "[x](data:text/html;base64,PHNjcmlwdD4=)", "<p>x</p>"},
+		{"relative link", "[x](/admin)", "<p>x</p>"},
+		{"control character in link", "[x](java\tscript:alert)", "<p>x</p>"},
+		{
+			"quotes in link target",
+			`[x](https://a.com/"onmouseover="alert)`,
+			`<p><a href="https://a.com/&#34;onmouseover=&#34;alert" rel="nofollow noopener noreferrer">x</a></p>`,
+		},
+		{"html in code", "`<b>`", "<p><code>&lt;b&gt;</code></p>"},
+		{
+			"nested brackets",
+			"[[a](https://a.com)](https://b.com)",
+			`<p><a href="https://a.com" rel="nofollow noopener noreferrer">[a</a>](https://b.com)</p>`,
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, RenderMarkdown(test.source))
+		})
+	}
+}
+
+func TestMarkdownLinkTargets(t *testing.T) {
+	source := "[a](https://a.com) and\n\n- [b](javascript:alert(1))\n> [c](mailto:c@example.com)"
+	assert.Equal(t, []string{"https://a.com", "javascript:alert(1", "mailto:c@example.com"}, MarkdownLinkTargets(source))
+}
+
+func TestIsSafeLinkURL(t *testing.T) {
+	assert.True(t, IsSafeLinkURL("https://example.com/path?q=1"))
+	assert.True(t, IsSafeLinkURL("http://example.com"))
+	assert.True(t, IsSafeLinkURL("mailto:someone@example.com"))
+	assert.False(t, IsSafeLinkURL("javascript:alert(1)"))
+	assert.False(t, IsSafeLinkURL("vbscript:msgbox"))
+	assert.False(t, IsSafeLinkURL("https:///no-host"))
+	assert.False(t, IsSafeLinkURL("//example.com"))
+	assert.False(t, IsSafeLinkURL(""))
+}
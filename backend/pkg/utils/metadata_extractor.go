@@ -0,0 +1,243 @@
+package utils
+
+import (
+	"encoding/json"
+	"io"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// ScrapedPage is a fetched page, parsed once and shared by all extractors.
+type ScrapedPage struct {
+	URL *url.URL
+	// Title is the text of the <title> element.
+	Title string
+	// Meta maps the property or name of meta tags, lowercased, to their
+	// contents in document order.
+	Meta map[string][]string
+	// JSONLD holds the JSON-LD objects of the page, with @graph members
+	// listed as objects of their own.
+	JSONLD []map[string]any
+	// OEmbedURL is the JSON oEmbed endpoint the page links to, if any.
+	OEmbedURL string
+	// CanonicalURL is the <link rel="canonical"> of the page, if it is on
+	// the same site. Canonical links to other sites are ignored, or any
+	// page could claim to be a popular article.
+	CanonicalURL string
+	// HasAudio is set if the page embeds or links an audio file, through an
+	// <audio> element or an audio enclosure.
+	HasAudio bool
+	Root     *html.Node
+}
+
+// MetaContent returns the first meta tag content for key, or "".
+func (p *ScrapedPage) MetaContent(key string) string {
+	if values := p.Meta[key]; len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// MetadataExtractor fills in metadata from a page. Extractors only set
+// fields that are still empty, so earlier extractors take precedence.
+type MetadataExtractor interface {
+	Extract(page *ScrapedPage, metadata *LinkMetadata)
+}
+
+// SiteExtractor is a MetadataExtractor for particular sites. Site
+// extractors run before the generic ones, so they can correct what the
+// generic ones would get wrong.
+type SiteExtractor interface {
+	MetadataExtractor
+	Matches(page *ScrapedPage) bool
+}
+
+// MetadataPipeline extracts link metadata by running the site extractors
+// that match a page, then JSON-LD, then meta tags, then oEmbed. The type is
+// classified with ClassifyPostType unless a site extractor set it.
+type MetadataPipeline struct {
+	sites   []SiteExtractor
+	generic []MetadataExtractor
+}
+
+// NewMetadataPipeline returns a pipeline with the built-in extractors.
+// fetchOEmbed loads oEmbed responses; oEmbed is skipped if it is nil.
+func NewMetadataPipeline(fetchOEmbed OEmbedFetcher) *MetadataPipeline {
+	pipeline := &MetadataPipeline{
+		generic: []MetadataExtractor{
+			JSONLDExtractor{},
+			MetaTagExtractor{},
+		},
+	}
+	if fetchOEmbed != nil {
+		pipeline.generic = append(pipeline.generic, OEmbedExtractor{Fetch: fetchOEmbed})
+	}
+	for _, site := range DefaultSiteExtractors() {
+		pipeline.RegisterSite(site)
+	}
+	return pipeline
+}
+
+// RegisterSite adds a site extractor. Site extractors run in the order
+// they were registered.
+func (p *MetadataPipeline) RegisterSite(site SiteExtractor) {
+	p.sites = append(p.sites, site)
+}
+
+// Extract parses the HTML page at pageURL and returns its metadata.
+// Fields that can't be found are empty.
+func (p *MetadataPipeline) Extract(pageURL string, body io.Reader) (*LinkMetadata, error) {
+	page, err := ParsePage(pageURL, body)
+	if err != nil {
+		return nil, err
+	}
+	metadata := &LinkMetadata{URL: pageURL}
+	if page.CanonicalURL != "" {
+		metadata.URL = page.CanonicalURL
+	}
+	// NormalizeURL can't fail for a URL the page was fetched from
+	metadata.CanonicalURL, _ = NormalizeURL(metadata.URL)
+	for _, site := range p.sites {
+		if site.Matches(page) {
+			site.Extract(page, metadata)
+		}
+	}
+	setTypeIfEmpty(&metadata.Type, ClassifyPostType(page))
+	for _, extractor := range p.generic {
+		extractor.Extract(page, metadata)
+	}
+	setIfEmpty(&metadata.Title, page.Title)
+	return metadata, nil
+}
+
+// ParsePage parses an HTML page for the extractors.
+func ParsePage(pageURL string, body io.Reader) (*ScrapedPage, error) {
+	parsedURL, err := url.Parse(pageURL)
+	if err != nil {
+		return nil, err
+	}
+	root, err := html.Parse(body)
+	if err != nil {
+		return nil, err
+	}
+	page := &ScrapedPage{
+		URL:  parsedURL,
+		Meta: map[string][]string{},
+		Root: root,
+	}
+
+	var parseNode func(*html.Node)
+	parseNode = func(n *html.Node) {
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "meta":
+				key := attr(n, "property")
+				if key == "" {
+					key = attr(n, "name")
+				}
+				if key != "" {
+					key = strings.ToLower(key)
+					page.Meta[key] = append(page.Meta[key], strings.TrimSpace(attr(n, "content")))
+				}
+			case "title":
+				if n.FirstChild != nil && page.Title == "" {
+					page.Title = strings.TrimSpace(n.FirstChild.Data)
+				}
+			case "script":
+				if strings.EqualFold(attr(n, "type"), "application/ld+json") && n.FirstChild != nil {
+					page.JSONLD = append(page.JSONLD, parseJSONLD(n.FirstChild.Data)...)
+				}
+			case "link":
+				if strings.EqualFold(attr(n, "type"), "application/json+oembed") && page.OEmbedURL == "" {
+					page.OEmbedURL = resolveURL(parsedURL, attr(n, "href"))
+				}
+				if strings.EqualFold(attr(n, "rel"), "enclosure") && isAudio(attr(n, "type")) {
+					page.HasAudio = true
+				}
+				if strings.EqualFold(attr(n, "rel"), "canonical") && page.CanonicalURL == "" {
+					page.CanonicalURL = sameSiteURL(parsedURL, attr(n, "href"))
+				}
+			case "enclosure":
+				// RSS items embedded in the page
+				if isAudio(attr(n, "type")) {
+					page.HasAudio = true
+				}
+			case "audio":
+				page.HasAudio = true
+			}
+		}
+
+		for c := n.FirstChild; c != nil; c = c.NextSibling {
+			parseNode(c)
+		}
+	}
+	parseNode(root)
+	return page, nil
+}
+
+// parseJSONLD returns the objects of one JSON-LD script. Scripts may hold
+// an object, an array of objects, or an object with a @graph. Invalid JSON
+// is ignored, as pages often get it wrong.
+func parseJSONLD(source string) []map[string]any {
+	var value any
+	if err := json.Unmarshal([]byte(source), &value); err != nil {
+		return nil
+	}
+	objects := []map[string]any{}
+	var collect func(value any)
+	collect = func(value any) {
+		switch v := value.(type) {
+		case []any:
+			for _, item := range v {
+				collect(item)
+			}
+		case map[string]any:
+			objects = append(objects, v)
+			if graph, ok := v["@graph"]; ok {
+				collect(graph)
+			}
+		}
+	}
+	collect(value)
+	return objects
+}
+
+// sameSiteURL resolves ref against the page URL, or returns "" if it
+// points to another site.
+func sameSiteURL(base *url.URL, ref string) string {
+	resolved, err := url.Parse(resolveURL(base, ref))
+	if err != nil || (resolved.Scheme != "http" && resolved.Scheme != "https") || !sameSite(base, resolved) {
+		return ""
+	}
+	return resolved.String()
+}
+
+func isAudio(mimeType string) bool {
+	return strings.HasPrefix(strings.ToLower(strings.TrimSpace(mimeType)), "audio/")
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// resolveURL resolves a possibly relative reference against the page URL.
+func resolveURL(base *url.URL, ref string) string {
+	parsed, err := url.Parse(strings.TrimSpace(ref))
+	if err != nil {
+		return ""
+	}
+	return base.ResolveReference(parsed).String()
+}
+
+func setIfEmpty(field *string, value string) {
+	if *field == "" {
+		*field = strings.TrimSpace(value)
+	}
+}
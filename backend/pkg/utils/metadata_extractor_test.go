@@ -0,0 +1,176 @@
+package utils
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fixtureOEmbed serves the oEmbed fixture and records which URLs were
+// fetched.
+type fixtureOEmbed struct {
+	fetched []string
+}
+
+func (f *fixtureOEmbed) fetch(oembedURL string) ([]byte, error) {
+	f.fetched = append(f.fetched, oembedURL)
+	if oembedURL != "https://videos.example.com/oembed?url=https%3A%2F%2Fvideos.example.com%2Ftalk" {
+		return nil, errors.New("unexpected oembed request")
+	}
+	return os.ReadFile(filepath.Join("testdata", "metadata", "oembed.json"))
+}
+
+func extractFixture(t *testing.T, pipeline *MetadataPipeline, fixture string, pageURL string) *LinkMetadata {
+	t.Helper()
+	file, err := os.Open(filepath.Join("testdata", "metadata", fixture))
+	if err != nil {
+		t.Fatalf("Failed to open fixture: %v", err)
+	}
+	defer file.Close()
+	metadata, err := pipeline.Extract(pageURL, file)
+	if err != nil {
+		t.Fatalf("Failed to extract metadata: %v", err)
+	}
+	return metadata
+}
+
+func TestExtractMetadata(t *testing.T) {
+	tests := []struct {
+		fixture  string
+		url      string
+		expected LinkMetadata
+	}{
+		{"opengraph.html", "https://example.com/on-caring", LinkMetadata{
+			Title:       "On Caring",
+			Description: "A post about scope insensitivity.",
+			ImageURL:    "https://example.com/image.png",
+			SiteName:    "Example Blog",
+			Author:      "nate",
+			Type:        "article",
+		}},
+		{"youtube.html", "https://www.youtube.com/watch?v=zjkBMFhNj_g", LinkMetadata{
+			Title:       "Intro to Large Language Models",
+			Description: "This is a 1 hour general-audience introduction to Large Language Models.",
+			ImageURL:    "https://i.ytimg.com/vi/zjkBMFhNj_g/maxresdefault.jpg",
+			SiteName:    "YouTube",
+			Author:      "Andrej Karpathy",
+			Type:        "video",
+		}},
+		{"arxiv.html", "https://arxiv.org/abs/1706.03762", LinkMetadata{
+			Title:       "Attention Is All You Need",
+			Description: "The dominant sequence transduction models are based on complex recurrent or convolutional neural networks that include an encoder and a decoder.",
+			ImageURL:    "/static/browse/0.3.4/images/arxiv-logo-fb.png",
+			SiteName:    "arXiv",
+			Author:      "Ashish Vaswani, Noam Shazeer, Niki Parmar",
+			Type:        "paper",
+		}},
+		{"substack.html", "https://notes.example.com/p/the-case-for-boring-software", LinkMetadata{
+			Title:       "The Case for Boring Software",
+			Description: "Why the dullest tools win in the long run.",
+			ImageURL:    "https://substackcdn.com/image/fetch/cover.png",
+			SiteName:    "Engineering Notes",
+			Author:      "Jane Writer",
+			Type:        "blog",
+		}},
+		{"lesswrong.html", "https://www.lesswrong.com/posts/xg3hXCYQPJkwHyik2/the-best-textbooks-on-every-subject", LinkMetadata{
+			Title:       "The Best Textbooks on Every Subject",
+			Description: "For years, my self-education was stupid and wasteful.",
+			ImageURL:    "https://res.cloudinary.com/lesswrong-2-0/image/upload/v1654295382/new_mississippi_river_fjdmww.jpg",
+			SiteName:    "LessWrong",
+			Author:      "lukeprog",
+			Type:        "blog",
+		}},
+		{"podcast.html", "https://podcast.example.com/42", LinkMetadata{
+			Title:       "Episode 42: Forecasting",
+			Description: "We talk about calibration.",
+			ImageURL:    "https://podcast.example.com/ep42.jpg",
+			SiteName:    "Some Podcast",
+			Author:      "Ann Host, Bob Guest",
+			Type:        "podcast",
+		}},
+		{"oembed.html", "https://videos.example.com/talk", LinkMetadata{
+			Title:    "A Talk",
+			ImageURL: "https://videos.example.com/talk.jpg",
+			SiteName: "Example Videos",
+			Author:   "Carol Speaker",
+			Type:     "video",
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.fixture, func(t *testing.T) {
+			oembed := &fixtureOEmbed{}
+			metadata := extractFixture(t, NewMetadataPipeline(oembed.fetch), test.fixture, test.url)
+			test.expected.URL = test.url
+			test.expected.CanonicalURL, _ = NormalizeURL(test.url)
+			assert.Equal(t, test.expected, *metadata)
+		})
+	}
+}
+
+func TestOEmbedIsOnlyFetchedWhenNeeded(t *testing.T) {
+	oembed := &fixtureOEmbed{}
+	extractFixture(t, NewMetadataPipeline(oembed.fetch), "youtube.html", "https://www.youtube.com/watch?v=zjkBMFhNj_g")
+	assert.Empty(t, oembed.fetched)
+
+	extractFixture(t, NewMetadataPipeline(oembed.fetch), "oembed.html", "https://videos.example.com/talk")
+	assert.Equal(t, 1, len(oembed.fetched))
+}
+
+func TestExtractWithoutOEmbed(t *testing.T) {
+	metadata := extractFixture(t, NewMetadataPipeline(nil), "oembed.html", "https://videos.example.com/talk")
+	assert.Equal(t, "A Talk", metadata.Title)
+	assert.Empty(t, metadata.Author)
+}
+
+type fixedSiteExtractor struct{}
+
+func (fixedSiteExtractor) Matches(page *ScrapedPage) bool {
+	return page.URL.Hostname() == "example.com"
+}
+
+func (fixedSiteExtractor) Extract(page *ScrapedPage, metadata *LinkMetadata) {
+	metadata.Author = "Site Extractor"
+}
+
+func TestRegisterSite(t *testing.T) {
+	pipeline := NewMetadataPipeline(nil)
+	pipeline.RegisterSite(fixedSiteExtractor{})
+	metadata := extractFixture(t, pipeline, "opengraph.html", "https://example.com/on-caring")
+	assert.Equal(t, "Site Extractor", metadata.Author)
+	metadata = extractFixture(t, pipeline, "opengraph.html", "https://other.example.org/on-caring")
+	assert.Equal(t, "nate", metadata.Author)
+}
+
+func TestExtractUsesSameSiteCanonicalLink(t *testing.T) {
+	tests := []struct {
+		name         string
+		url          string
+		canonical    string
+		expectedURL  string
+		canonicalURL string
+	}{
+		{"same site", "https://m.example.com/post?utm_source=feed", "/post",
+			"https://m.example.com/post", "https://example.com/post"},
+		{"www", "https://example.com/post?share=1", "https://www.example.com/post/",
+			"https://www.example.com/post/", "https://example.com/post"},
+		{"other site", "https://spam.example.org/post", "https://example.com/popular",
+			"https://spam.example.org/post", "https://spam.example.org/post"},
+		{"not http", "https://example.com/post", "javascript:alert(1)",
+			"https://example.com/post", "https://example.com/post"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			body := `<html><head><link rel="canonical" href="` + test.canonical + `"></head></html>`
+			metadata, err := NewMetadataPipeline(nil).Extract(test.url, strings.NewReader(body))
+			if err != nil {
+				t.Fatalf("Failed to extract metadata: %v", err)
+			}
+			assert.Equal(t, test.expectedURL, metadata.URL)
+			assert.Equal(t, test.canonicalURL, metadata.CanonicalURL)
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package utils
+
+import (
+	"sane-discourse-backend/pkg/types"
+	"strings"
+)
+
+// jsonLDTypes maps the schema.org types JSON-LD extraction understands to
+// the post type they describe.
+var jsonLDTypes = map[string]types.PostType{
+	"Article":          types.PostTypeArticle,
+	"NewsArticle":      types.PostTypeArticle,
+	"BlogPosting":      types.PostTypeBlog,
+	"ScholarlyArticle": types.PostTypePaper,
+	"VideoObject":      types.PostTypeVideo,
+	"PodcastEpisode":   types.PostTypePodcast,
+}
+
+// JSONLDExtractor reads schema.org metadata from the first JSON-LD object
+// of a known type.
+type JSONLDExtractor struct{}
+
+func (JSONLDExtractor) Extract(page *ScrapedPage, metadata *LinkMetadata) {
+	object, _ := findJSONLD(page)
+	if object == nil {
+		return
+	}
+	setIfEmpty(&metadata.Title, jsonLDString(object["headline"]))
+	setIfEmpty(&metadata.Title, jsonLDString(object["name"]))
+	setIfEmpty(&metadata.Description, jsonLDString(object["description"]))
+	setIfEmpty(&metadata.ImageURL, jsonLDURL(object["image"]))
+	setIfEmpty(&metadata.ImageURL, jsonLDURL(object["thumbnailUrl"]))
+	setIfEmpty(&metadata.Author, strings.Join(jsonLDNames(object["author"]), ", "))
+	setIfEmpty(&metadata.Author, strings.Join(jsonLDNames(object["creator"]), ", "))
+	setIfEmpty(&metadata.SiteName, strings.Join(jsonLDNames(object["partOfSeries"]), ", "))
+	setIfEmpty(&metadata.SiteName, strings.Join(jsonLDNames(object["publisher"]), ", "))
+}
+
+func findJSONLD(page *ScrapedPage) (map[string]any, string) {
+	for _, object := range page.JSONLD {
+		for _, schemaType := range jsonLDStrings(object["@type"]) {
+			if _, ok := jsonLDTypes[schemaType]; ok {
+				return object, schemaType
+			}
+		}
+	}
+	return nil, ""
+}
+
+// jsonLDString returns a text value, which may also be given as a list.
+func jsonLDString(value any) string {
+	if values := jsonLDStrings(value); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+func jsonLDStrings(value any) []string {
+	switch v := value.(type) {
+	case string:
+		return []string{v}
+	case []any:
+		values := []string{}
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// jsonLDURL returns a URL given as a string, an ImageObject, or a list of
+// either.
+func jsonLDURL(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case map[string]any:
+		return jsonLDString(v["url"])
+	case []any:
+		for _, item := range v {
+			if url := jsonLDURL(item); url != "" {
+				return url
+			}
+		}
+	}
+	return ""
+}
+
+// jsonLDNames returns the names of people or organizations, which may be
+// given as objects, plain names, or a list of either.
+func jsonLDNames(value any) []string {
+	switch v := value.(type) {
+	case string:
+		if strings.HasPrefix(v, "http") {
+			return nil
+		}
+		return []string{v}
+	case map[string]any:
+		if name := jsonLDString(v["name"]); name != "" {
+			return []string{name}
+		}
+	case []any:
+		names := []string{}
+		for _, item := range v {
+			names = append(names, jsonLDNames(item)...)
+		}
+		return names
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package utils
+
+import (
+	"encoding/json"
+	"sane-discourse-backend/pkg/types"
+)
+
+// OEmbedFetcher loads the oEmbed response at oembedURL.
+type OEmbedFetcher func(oembedURL string) ([]byte, error)
+
+type oEmbedResponse struct {
+	Type         string `json:"type"`
+	Title        string `json:"title"`
+	AuthorName   string `json:"author_name"`
+	ProviderName string `json:"provider_name"`
+	ThumbnailURL string `json:"thumbnail_url"`
+}
+
+// OEmbedExtractor reads the oEmbed endpoint a page links to. It is only
+// fetched if it could fill in a field that is still empty.
+type OEmbedExtractor struct {
+	Fetch OEmbedFetcher
+}
+
+func (e OEmbedExtractor) Extract(page *ScrapedPage, metadata *LinkMetadata) {
+	if page.OEmbedURL == "" {
+		return
+	}
+	if metadata.Title != "" && metadata.Author != "" && metadata.SiteName != "" && metadata.ImageURL != "" {
+		return
+	}
+	body, err := e.Fetch(page.OEmbedURL)
+	if err != nil {
+		// oEmbed is optional, the page's own metadata is used without it
+		return
+	}
+	var response oEmbedResponse
+	if err = json.Unmarshal(body, &response); err != nil {
+		return
+	}
+	setIfEmpty(&metadata.Title, response.Title)
+	setIfEmpty(&metadata.Author, response.AuthorName)
+	setIfEmpty(&metadata.SiteName, response.ProviderName)
+	setIfEmpty(&metadata.ImageURL, response.ThumbnailURL)
+	if response.Type == "video" {
+		setTypeIfEmpty(&metadata.Type, types.PostTypeVideo)
+	}
+}
@@ -0,0 +1,145 @@
+package utils
+
+import (
+	"net/url"
+	"sane-discourse-backend/pkg/types"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// DefaultSiteExtractors returns the built-in site extractors.
+func DefaultSiteExtractors() []SiteExtractor {
+	return []SiteExtractor{
+		YouTubeExtractor{},
+		ArxivExtractor{},
+		SubstackExtractor{},
+		ForumMagnumExtractor{},
+	}
+}
+
+// YouTubeExtractor reads the channel of a video, which YouTube only gives
+// as microdata.
+type YouTubeExtractor struct{}
+
+func (YouTubeExtractor) Matches(page *ScrapedPage) bool {
+	return hostIs(page.URL, "youtube.com", "youtu.be")
+}
+
+func (YouTubeExtractor) Extract(page *ScrapedPage, metadata *LinkMetadata) {
+	if author := findItemprop(page.Root, "author"); author != nil {
+		if name := findItemprop(author, "name"); name != nil {
+			setIfEmpty(&metadata.Author, itempropValue(name))
+		}
+	}
+	setIfEmpty(&metadata.SiteName, "YouTube")
+	setTypeIfEmpty(&metadata.Type, types.PostTypeVideo)
+}
+
+// ArxivExtractor reads the citation meta tags of arXiv abstract pages,
+// which list every author.
+type ArxivExtractor struct{}
+
+func (ArxivExtractor) Matches(page *ScrapedPage) bool {
+	return hostIs(page.URL, "arxiv.org")
+}
+
+func (ArxivExtractor) Extract(page *ScrapedPage, metadata *LinkMetadata) {
+	setIfEmpty(&metadata.Title, page.MetaContent("citation_title"))
+	setIfEmpty(&metadata.Description, page.MetaContent("citation_abstract"))
+	setIfEmpty(&metadata.Author, citationAuthors(page))
+	setIfEmpty(&metadata.SiteName, "arXiv")
+	setTypeIfEmpty(&metadata.Type, types.PostTypePaper)
+}
+
+// SubstackExtractor handles Substack newsletters, including those on
+// custom domains, which are recognized by their images on Substack's CDN.
+type SubstackExtractor struct{}
+
+func (SubstackExtractor) Matches(page *ScrapedPage) bool {
+	if hostIs(page.URL, "substack.com") {
+		return true
+	}
+	image, err := url.Parse(page.MetaContent("og:image"))
+	return err == nil && hostIs(image, "substackcdn.com")
+}
+
+func (SubstackExtractor) Extract(page *ScrapedPage, metadata *LinkMetadata) {
+	setIfEmpty(&metadata.Author, page.MetaContent("author"))
+	setTypeIfEmpty(&metadata.Type, types.PostTypeBlog)
+}
+
+// ForumMagnumExtractor handles LessWrong, the EA Forum and the Alignment
+// Forum, which run the same software.
+type ForumMagnumExtractor struct{}
+
+var forumMagnumSites = map[string]string{
+	"lesswrong.com":               "LessWrong",
+	"forum.effectivealtruism.org": "Effective Altruism Forum",
+	"alignmentforum.org":          "AI Alignment Forum",
+}
+
+func (ForumMagnumExtractor) Matches(page *ScrapedPage) bool {
+	_, ok := forumMagnumSites[forumMagnumHost(page.URL)]
+	return ok
+}
+
+func (ForumMagnumExtractor) Extract(page *ScrapedPage, metadata *LinkMetadata) {
+	setIfEmpty(&metadata.Title, page.MetaContent("citation_title"))
+	setIfEmpty(&metadata.Author, citationAuthors(page))
+	setIfEmpty(&metadata.SiteName, forumMagnumSites[forumMagnumHost(page.URL)])
+	setTypeIfEmpty(&metadata.Type, types.PostTypeBlog)
+}
+
+func forumMagnumHost(u *url.URL) string {
+	return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+}
+
+// hostIs reports whether u is on one of the domains or their subdomains.
+func hostIs(u *url.URL, domains ...string) bool {
+	host := strings.ToLower(u.Hostname())
+	for _, domain := range domains {
+		if host == domain || strings.HasSuffix(host, "."+domain) {
+			return true
+		}
+	}
+	return false
+}
+
+// citationAuthors joins the citation_author meta tags, turning the
+// "Last, First" form into "First Last".
+func citationAuthors(page *ScrapedPage) string {
+	authors := []string{}
+	for _, author := range page.Meta["citation_author"] {
+		if last, first, ok := strings.Cut(author, ","); ok {
+			author = strings.TrimSpace(first) + " " + strings.TrimSpace(last)
+		}
+		if author != "" {
+			authors = append(authors, author)
+		}
+	}
+	return strings.Join(authors, ", ")
+}
+
+// findItemprop returns the first element below n with the given itemprop.
+func findItemprop(n *html.Node, itemprop string) *html.Node {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		if c.Type == html.ElementNode && attr(c, "itemprop") == itemprop {
+			return c
+		}
+		if found := findItemprop(c, itemprop); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+func itempropValue(n *html.Node) string {
+	if content := attr(n, "content"); content != "" {
+		return content
+	}
+	if n.FirstChild != nil && n.FirstChild.Type == html.TextNode {
+		return n.FirstChild.Data
+	}
+	return ""
+}
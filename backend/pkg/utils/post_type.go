@@ -0,0 +1,78 @@
+package utils
+
+import (
+	"net/url"
+	"regexp"
+	"sane-discourse-backend/pkg/types"
+	"strings"
+)
+
+// doiPathPattern matches publisher links that embed a DOI, like
+// /doi/10.1145/3368089.
+var doiPathPattern = regexp.MustCompile(`/doi/(abs/|full/|pdf/)?10\.\d{4,}/`)
+
+// ClassifyPostType decides what kind of post a page is. URL patterns are
+// the most reliable signal, then JSON-LD, then OpenGraph. Pages with audio
+// are podcasts unless OpenGraph says they are videos, since podcast hosts
+// often mark episodes as plain articles. It returns "" if the type is
+// unknown.
+func ClassifyPostType(page *ScrapedPage) types.PostType {
+	if postType := PostTypeForURL(page.URL); postType != "" {
+		return postType
+	}
+	if _, schemaType := findJSONLD(page); schemaType != "" {
+		return jsonLDTypes[schemaType]
+	}
+	ogType := PostTypeForOpenGraph(page.MetaContent("og:type"))
+	if ogType == types.PostTypeVideo || ogType == types.PostTypePodcast {
+		return ogType
+	}
+	if hasAudio(page) {
+		return types.PostTypePodcast
+	}
+	return ogType
+}
+
+// PostTypeForURL classifies links whose URL alone gives away the type:
+// arXiv and DOI links are papers, YouTube and Vimeo links are videos.
+func PostTypeForURL(u *url.URL) types.PostType {
+	switch {
+	case hostIs(u, "arxiv.org", "doi.org", "biorxiv.org", "medrxiv.org"):
+		return types.PostTypePaper
+	case doiPathPattern.MatchString(u.Path):
+		return types.PostTypePaper
+	case hostIs(u, "youtube.com", "youtu.be", "vimeo.com"):
+		return types.PostTypeVideo
+	}
+	return ""
+}
+
+// PostTypeForOpenGraph maps an og:type onto a post type. Generic types
+// like "website" are unknown.
+func PostTypeForOpenGraph(ogType string) types.PostType {
+	ogType = strings.ToLower(strings.TrimSpace(ogType))
+	switch {
+	case ogType == "article":
+		return types.PostTypeArticle
+	case ogType == "blog":
+		return types.PostTypeBlog
+	case ogType == "video" || strings.HasPrefix(ogType, "video."):
+		return types.PostTypeVideo
+	case ogType == "podcast" || strings.HasPrefix(ogType, "podcast."):
+		return types.PostTypePodcast
+	}
+	return ""
+}
+
+func hasAudio(page *ScrapedPage) bool {
+	return page.HasAudio ||
+		page.MetaContent("og:audio") != "" ||
+		page.MetaContent("og:audio:url") != "" ||
+		page.MetaContent("og:audio:secure_url") != ""
+}
+
+func setTypeIfEmpty(field *types.PostType, value types.PostType) {
+	if *field == "" {
+		*field = value
+	}
+}
@@ -0,0 +1,92 @@
+package utils
+
+import (
+	"net/url"
+	"sane-discourse-backend/pkg/types"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPostTypeForURL(t *testing.T) {
+	tests := map[string]types.PostType{
+		"https://arxiv.org/abs/1706.03762":                       types.PostTypePaper,
+		"https://export.arxiv.org/pdf/1706.03762":                types.PostTypePaper,
+		"https://doi.org/10.1038/nature14539":                    types.PostTypePaper,
+		"https://dl.acm.org/doi/10.1145/3368089.3409747":         types.PostTypePaper,
+		"https://onlinelibrary.wiley.com/doi/abs/10.1111/j.1468": types.PostTypePaper,
+		"https://www.youtube.com/watch?v=zjkBMFhNj_g":            types.PostTypeVideo,
+		"https://m.youtube.com/watch?v=zjkBMFhNj_g":              types.PostTypeVideo,
+		"https://youtu.be/zjkBMFhNj_g":                           types.PostTypeVideo,
+		"https://vimeo.com/76979871":                             types.PostTypeVideo,
+		"https://example.com/blog/doi-explained":                 "",
+		"https://notyoutube.com/watch":                           "",
+	}
+	for rawURL, expected := range tests {
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			t.Fatalf("Failed to parse URL: %v", err)
+		}
+		assert.Equal(t, expected, PostTypeForURL(u), rawURL)
+	}
+}
+
+func TestPostTypeForOpenGraph(t *testing.T) {
+	tests := map[string]types.PostType{
+		"article":         types.PostTypeArticle,
+		"Article":         types.PostTypeArticle,
+		"blog":            types.PostTypeBlog,
+		"video.other":     types.PostTypeVideo,
+		"video.episode":   types.PostTypeVideo,
+		"podcast.episode": types.PostTypePodcast,
+		"website":         "",
+		"music.song":      "",
+		"":                "",
+	}
+	for ogType, expected := range tests {
+		assert.Equal(t, expected, PostTypeForOpenGraph(ogType), ogType)
+	}
+}
+
+func TestClassifyPostType(t *testing.T) {
+	tests := []struct {
+		name     string
+		url      string
+		html     string
+		expected types.PostType
+	}{
+		{"url beats og:type", "https://arxiv.org/abs/1706.03762",
+			`<meta property="og:type" content="website">`, types.PostTypePaper},
+		{"json-ld beats og:type", "https://example.com/paper",
+			`<meta property="og:type" content="article"><script type="application/ld+json">{"@type": "ScholarlyArticle"}</script>`,
+			types.PostTypePaper},
+		{"og:audio", "https://example.com/episode",
+			`<meta property="og:audio" content="https://example.com/episode.mp3">`, types.PostTypePodcast},
+		{"audio enclosure", "https://example.com/episode",
+			`<link rel="enclosure" type="audio/mpeg" href="https://example.com/episode.mp3">`, types.PostTypePodcast},
+		{"audio beats article", "https://example.com/post",
+			`<meta property="og:type" content="article"><audio src="narration.mp3"></audio>`, types.PostTypePodcast},
+		{"video with audio", "https://example.com/talk",
+			`<meta property="og:type" content="video.other"><meta property="og:audio" content="talk.mp3">`,
+			types.PostTypeVideo},
+		{"video enclosure", "https://example.com/talk",
+			`<link rel="enclosure" type="video/mp4" href="talk.mp4">`, ""},
+		{"unknown", "https://example.com/",
+			`<meta property="og:type" content="website">`, ""},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			page, err := ParsePage(test.url, strings.NewReader("<html><head>"+test.html+"</head></html>"))
+			if err != nil {
+				t.Fatalf("Failed to parse page: %v", err)
+			}
+			assert.Equal(t, test.expected, ClassifyPostType(page))
+		})
+	}
+}
+
+func TestExtractClassifiesAudioPages(t *testing.T) {
+	metadata := extractFixture(t, NewMetadataPipeline(nil), "audio.html", "https://radio.example.com/interview")
+	assert.Equal(t, types.PostTypePodcast, metadata.Type)
+}
@@ -2,6 +2,8 @@ import axios from 'axios';
 import type {
     User,
     Post,
+    FeedSort,
+    PostPage,
     CreatePostRequest,
     AddPostRequest,
     UserPostsRequest,
@@ -10,7 +12,12 @@ import type {
     AddComponentRequest,
     MoveComponentRequest,
     UpdateComponentRequest,
-    DeleteComponentRequest
+    DeleteComponentRequest,
+    BatchRequest,
+    UserpageRevision,
+    UserpageSummary,
+    CreateUserpageRequest,
+    UpdateUserpageRequest
 } from './types';
 
 const API_BASE_URL = 'http://localhost:3000';
@@ -42,19 +49,19 @@ export const addPost = async (request: AddPostRequest): Promise<Post> => {
     return response.data;
 };
 
-export const getUserPosts = async (): Promise<Post[]> => {
-    const response = await api.get('/user/posts');
+export const getUserPosts = async (cursor?: string, limit = 100): Promise<PostPage> => {
+    const response = await api.get('/user/posts', { params: { cursor, limit } });
     return response.data;
 };
 
-export const getFeed = async (): Promise<Post[]> => {
-    const response = await api.get('/home');
+export const getFeed = async (sort?: FeedSort, cursor?: string): Promise<PostPage> => {
+    const response = await api.get('/home', { params: { sort, cursor } });
     return response.data;
 };
 
 // Userpage endpoints
-export const getUserpage = async (): Promise<Userpage> => {
-    const response = await api.get('/userpage');
+export const getUserpage = async (page?: string): Promise<Userpage> => {
+    const response = await api.get('/userpage', { params: { page } });
     return response.data;
 };
 
@@ -76,4 +83,58 @@ export const deleteUserpageComponent = async (request: DeleteComponentRequest):
 export const moveUserpageComponent = async (request: MoveComponentRequest): Promise<Userpage> => {
     const response = await api.put('/userpage/component/move', request);
     return response.data;
-};
\ No newline at end of file
+};
+
+export const batchEditUserpage = async (request: BatchRequest): Promise<Userpage> => {
+    const response = await api.put('/userpage/batch', request);
+    return response.data;
+};
+
+export const getUserpageRevisions = async (before?: number, page?: string): Promise<UserpageRevision[]> => {
+    const response = await api.get('/userpage/revisions', { params: { before, page } });
+    return response.data;
+};
+
+export const getUserpageRevision = async (version: number, page?: string): Promise<UserpageRevision> => {
+    const response = await api.get(`/userpage/revisions/${version}`, { params: { page } });
+    return response.data;
+};
+
+export const undoUserpage = async (version: number, page?: string): Promise<Userpage> => {
+    const response = await api.put('/userpage/undo', { version, page });
+    return response.data;
+};
+
+export const restoreUserpageRevision = async (version: number, revision: number, page?: string): Promise<Userpage> => {
+    const response = await api.put('/userpage/restore', { version, revision, page });
+    return response.data;
+};
+
+export const getUserpages = async (): Promise<UserpageSummary[]> => {
+    const response = await api.get('/userpages');
+    return response.data;
+};
+
+export const createUserpage = async (request: CreateUserpageRequest): Promise<Userpage> => {
+    const response = await api.put('/userpages/create', request);
+    return response.data;
+};
+
+export const updateUserpage = async (request: UpdateUserpageRequest): Promise<Userpage> => {
+    const response = await api.put('/userpages/update', request);
+    return response.data;
+};
+
+export const deleteUserpage = async (page: string, version: number): Promise<void> => {
+    await api.delete('/userpages/delete', { data: { page, version } });
+};
+
+export const rotateUserpageShareToken = async (page: string, version: number): Promise<Userpage> => {
+    const response = await api.put('/userpages/rotate-token', { page, version });
+    return response.data;
+};
+
+export const reorderUserpages = async (pages: string[]): Promise<UserpageSummary[]> => {
+    const response = await api.put('/userpages/reorder', { pages });
+    return response.data;
+};
@@ -1,5 +1,6 @@
 import { useState } from 'react';
 import type { Post } from '../types';
+import { POST_TYPES } from '../types';
 
 interface EditablePostCardProps {
     post: Post;
@@ -125,11 +126,9 @@ export const EditablePostCard = ({ post, onAccept, onReject }: EditablePostCardP
                             <label style={{ display: 'block', fontWeight: 'bold', marginBottom: '0.25rem' }}>
                                 Type:
                             </label>
-                            <input
-                                type="text"
+                            <select
                                 value={editedPost.type}
                                 onChange={(e) => handleFieldChange('type', e.target.value)}
-                                placeholder={isFieldEmpty(post.type) ? "Add type..." : ""}
                                 style={{
                                     width: '100%',
                                     padding: '0.5rem',
@@ -138,7 +137,12 @@ export const EditablePostCard = ({ post, onAccept, onReject }: EditablePostCardP
                                     backgroundColor: isFieldEmpty(post.type) ? '#fff7e6' : 'white',
                                     color: '#333'
                                 }}
-                            />
+                            >
+                                <option value="">Unknown</option>
+                                {POST_TYPES.map(postType => (
+                                    <option key={postType} value={postType}>{postType}</option>
+                                ))}
+                            </select>
                         </div>
 
                         <div>
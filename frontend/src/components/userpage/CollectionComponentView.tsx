@@ -0,0 +1,75 @@
+import type { CollectionComponentData, UserpageComponent } from '../../types';
+import { DividerComponentView } from './DividerComponentView';
+import { ImageComponentView } from './ImageComponentView';
+import { LinkListComponentView } from './LinkListComponentView';
+import { QuoteComponentView } from './QuoteComponentView';
+
+interface CollectionComponentViewProps {
+    component: CollectionComponentData;
+}
+
+// Read-only rendering of the embedded section. The server resolves sections
+// one level deep, so nested collections are only shown as a reference.
+const renderEmbedded = (component: UserpageComponent) => {
+    if (component.header) {
+        // One level smaller than on the embedded page itself
+        const Heading = (['h2', 'h3', 'h4', 'h5'] as const)[component.header.size - 1] ?? 'h5';
+        return <Heading style={{ margin: '0.5em 0' }}>{component.header.content}</Heading>;
+    } else if (component.paragraph) {
+        if (component.paragraph.content_html) {
+            // Sanitized by the server
+            return <div dangerouslySetInnerHTML={{ __html: component.paragraph.content_html }} />;
+        }
+        return <p style={{ margin: '0 0 1em 0' }}>{component.paragraph.content}</p>;
+    } else if (component.post) {
+        const post = component.post.post;
+        if (!post) {
+            return <p style={{ color: 'var(--text-dim)' }}>This post has been deleted.</p>;
+        }
+        return (
+            <p style={{ margin: '0 0 1em 0' }}>
+                <a href={post.url} target="_blank" rel="noopener noreferrer">{post.title}</a>
+                {post.site_name && <span style={{ color: 'var(--text-dim)' }}> · {post.site_name}</span>}
+            </p>
+        );
+    } else if (component.divider) {
+        return <DividerComponentView component={component.divider} />;
+    } else if (component.quote) {
+        return <QuoteComponentView component={component.quote} />;
+    } else if (component.link_list) {
+        return <LinkListComponentView component={component.link_list} />;
+    } else if (component.image) {
+        return <ImageComponentView component={component.image} />;
+    } else if (component.collection) {
+        return <p style={{ color: 'var(--text-dim)' }}>Embedded collection</p>;
+    }
+    return null;
+};
+
+export const CollectionComponentView = ({ component }: CollectionComponentViewProps) => {
+    if (component.missing || !component.components) {
+        return (
+            <p style={{ color: 'var(--text-dim)' }}>
+                This collection is no longer available.
+            </p>
+        );
+    }
+
+    return (
+        <section style={{
+            margin: '1.5em 0',
+            padding: '0.5em 1em',
+            border: '1px solid var(--border-color)',
+            borderRadius: '4px',
+        }}>
+            {component.user && (
+                <div style={{ fontSize: '0.85em', color: 'var(--text-dim)' }}>
+                    From <a href={`/u/${component.user.username}`}>{component.user.username}</a>
+                </div>
+            )}
+            {component.components.map((embedded, index) => (
+                <div key={embedded.id ?? index}>{renderEmbedded(embedded)}</div>
+            ))}
+        </section>
+    );
+};
@@ -0,0 +1,22 @@
+import type { ImageComponentData } from '../../types';
+
+interface ImageComponentViewProps {
+    component: ImageComponentData;
+}
+
+export const ImageComponentView = ({ component }: ImageComponentViewProps) => {
+    return (
+        <figure style={{ margin: '1.5em 0' }}>
+            <img
+                src={component.url}
+                alt={component.alt}
+                style={{ maxWidth: '100%', display: 'block', margin: '0 auto' }}
+            />
+            {component.caption && (
+                <figcaption style={{ marginTop: '0.5em', textAlign: 'center', color: 'var(--text-dim)' }}>
+                    {component.caption}
+                </figcaption>
+            )}
+        </figure>
+    );
+};
@@ -0,0 +1,22 @@
+import type { LinkListComponentData } from '../../types';
+
+interface LinkListComponentViewProps {
+    component: LinkListComponentData;
+}
+
+export const LinkListComponentView = ({ component }: LinkListComponentViewProps) => {
+    return (
+        <div style={{ margin: '0 0 1em 0' }}>
+            {component.title && <strong>{component.title}</strong>}
+            <ul style={{ margin: '0.5em 0 0 0' }}>
+                {component.links.map((link, index) => (
+                    <li key={index}>
+                        <a href={link.url} target="_blank" rel="noopener noreferrer">
+                            {link.title || link.url}
+                        </a>
+                    </li>
+                ))}
+            </ul>
+        </div>
+    );
+};
@@ -15,6 +15,13 @@ export const ParagraphComponentView = ({
     const [isEditing, setIsEditing] = useState(false);
     const textRef = useRef<HTMLParagraphElement>(null);
 
+    // The editable source replaces the rendered HTML, so focus it
+    useEffect(() => {
+        if (isEditing) {
+            textRef.current?.focus();
+        }
+    }, [isEditing]);
+
     const handleClick = () => {
         if (isEditMode && !isEditing) {
             setIsEditing(true);
@@ -52,10 +59,23 @@ export const ParagraphComponentView = ({
         style.transition = 'background-color 0.2s';
     }
 
+    // Outside of editing show the HTML the server rendered from the Markdown.
+    // The server sanitizes it, so it is safe to insert as is.
+    if (!isEditing && component.content_html) {
+        return (
+            <div
+                style={style}
+                className={`markdown ${isEditMode ? 'editable-content' : ''}`}
+                onClick={handleClick}
+                dangerouslySetInnerHTML={{ __html: component.content_html }}
+            />
+        );
+    }
+
     return (
         <p
             ref={textRef}
-            style={style}
+            style={{ ...style, whiteSpace: isEditing ? 'pre-wrap' : undefined }}
             className={isEditMode ? 'editable-content' : ''}
             contentEditable={isEditMode && isEditing}
             suppressContentEditableWarning
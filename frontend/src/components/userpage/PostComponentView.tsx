@@ -3,6 +3,7 @@ import { useQuery, useMutation, useQueryClient } from '@tanstack/react-query';
 import { getUserPosts, createPostFromUrl, addPost } from '../../api';
 import { PostCard } from '../PostCard';
 import type { PostComponentData, Post } from '../../types';
+import { POST_TYPES } from '../../types';
 
 interface PostComponentViewProps {
     component: PostComponentData;
@@ -21,10 +22,10 @@ export const PostComponentView = ({
     // Fetch user posts to find the post by ID
     const { data: userPosts } = useQuery({
         queryKey: ['userPosts'],
-        queryFn: getUserPosts,
+        queryFn: () => getUserPosts(),
     });
 
-    const post = userPosts?.find(p => p.id === component.post_id);
+    const post = userPosts?.posts.find(p => p.id === component.post_id);
 
     const handleSizeChange = (newSize: 2 | 3) => {
         setSelectedSize(newSize);
@@ -193,12 +194,16 @@ export const PostCreator = ({ onPostCreated, onCancel }: PostCreatorProps) => {
 
                     <div>
                         <label className="form-label">Type</label>
-                        <input
+                        <select
                             className="form-input"
-                            type="text"
                             value={pendingPost.type}
                             onChange={(e) => handleEditField('type', e.target.value)}
-                        />
+                        >
+                            <option value="">Unknown</option>
+                            {POST_TYPES.map(postType => (
+                                <option key={postType} value={postType}>{postType}</option>
+                            ))}
+                        </select>
                     </div>
 
                     <div>
@@ -0,0 +1,23 @@
+import type { QuoteComponentData } from '../../types';
+
+interface QuoteComponentViewProps {
+    component: QuoteComponentData;
+}
+
+export const QuoteComponentView = ({ component }: QuoteComponentViewProps) => {
+    return (
+        <blockquote style={{
+            margin: '1.5em 0',
+            padding: '0 0 0 1em',
+            borderLeft: '3px solid var(--border-color)',
+            fontStyle: 'italic',
+        }}>
+            <p style={{ margin: 0 }}>{component.content}</p>
+            {component.attribution && (
+                <footer style={{ marginTop: '0.5em', fontStyle: 'normal', color: 'var(--text-dim)' }}>
+                    — {component.attribution}
+                </footer>
+            )}
+        </blockquote>
+    );
+};
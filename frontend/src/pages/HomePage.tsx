@@ -3,10 +3,11 @@ import { getFeed } from '../api';
 import { PostCard } from '../components/PostCard';
 
 export const HomePage = () => {
-    const { data: posts, isLoading, error } = useQuery({
+    const { data: feedPage, isLoading, error } = useQuery({
         queryKey: ['feed'],
-        queryFn: getFeed,
+        queryFn: () => getFeed(),
     });
+    const posts = feedPage?.posts;
 
     if (isLoading) {
         return (
@@ -1,10 +1,15 @@
 import { useState } from 'react';
 import { useQuery, useMutation, useQueryClient } from '@tanstack/react-query';
+import { isAxiosError } from 'axios';
 import { getUserpage, addUserpageComponent, updateUserpageComponent, deleteUserpageComponent, moveUserpageComponent } from '../api';
 import { HeaderComponentView } from '../components/userpage/HeaderComponentView';
 import { ParagraphComponentView } from '../components/userpage/ParagraphComponentView';
 import { PostComponentView, PostCreator } from '../components/userpage/PostComponentView';
 import { DividerComponentView } from '../components/userpage/DividerComponentView';
+import { QuoteComponentView } from '../components/userpage/QuoteComponentView';
+import { LinkListComponentView } from '../components/userpage/LinkListComponentView';
+import { ImageComponentView } from '../components/userpage/ImageComponentView';
+import { CollectionComponentView } from '../components/userpage/CollectionComponentView';
 import { ComponentMenu, type ComponentType } from '../components/userpage/ComponentMenu';
 import type { User, UserpageComponent } from '../types';
 
@@ -33,6 +38,18 @@ export const Userpage = ({ currentUser, setCurrentUser }: UserPageProps) => {
         enabled: !!currentUser,
     });
 
+    // A 409 means the page was changed elsewhere, e.g. in another tab.
+    // The response carries the current page, so show that instead.
+    const handleEditError = (action: string) => (error: unknown) => {
+        if (isAxiosError(error) && error.response?.status === 409) {
+            queryClient.setQueryData(['userpage'], error.response.data);
+            alert('Your page was changed elsewhere and has been reloaded. Please try again.');
+            return;
+        }
+        console.error(`Failed to ${action} component:`, error);
+        alert(`Failed to ${action} component. Please try again.`);
+    };
+
     // Add component mutation
     const addComponentMutation = useMutation({
         mutationFn: addUserpageComponent,
@@ -40,10 +57,7 @@ export const Userpage = ({ currentUser, setCurrentUser }: UserPageProps) => {
             // Update the cache with the returned userpage
             queryClient.setQueryData(['userpage'], updatedUserpage);
         },
-        onError: (error) => {
-            console.error('Failed to add component:', error);
-            alert('Failed to add component. Please try again.');
-        }
+        onError: handleEditError('add')
     });
 
     // Update component mutation
@@ -52,10 +66,7 @@ export const Userpage = ({ currentUser, setCurrentUser }: UserPageProps) => {
         onSuccess: (updatedUserpage) => {
             queryClient.setQueryData(['userpage'], updatedUserpage);
         },
-        onError: (error) => {
-            console.error('Failed to update component:', error);
-            alert('Failed to update component. Please try again.');
-        }
+        onError: handleEditError('update')
     });
 
     // Delete component mutation
@@ -64,10 +75,7 @@ export const Userpage = ({ currentUser, setCurrentUser }: UserPageProps) => {
         onSuccess: (updatedUserpage) => {
             queryClient.setQueryData(['userpage'], updatedUserpage);
         },
-        onError: (error) => {
-            console.error('Failed to delete component:', error);
-            alert('Failed to delete component. Please try again.');
-        }
+        onError: handleEditError('delete')
     });
 
     // Move component mutation
@@ -77,10 +85,7 @@ export const Userpage = ({ currentUser, setCurrentUser }: UserPageProps) => {
             // Update the cache with the returned userpage
             queryClient.setQueryData(['userpage'], updatedUserpage);
         },
-        onError: (error) => {
-            console.error('Failed to move component:', error);
-            alert('Failed to move component. Please try again.');
-        }
+        onError: handleEditError('move')
     });
 
     const handleGoogleLogin = () => {
@@ -123,6 +128,7 @@ export const Userpage = ({ currentUser, setCurrentUser }: UserPageProps) => {
         }
 
         addComponentMutation.mutate({
+            version: userpage!.version,
             index: menuState.insertIndex,
             component,
         });
@@ -141,6 +147,7 @@ export const Userpage = ({ currentUser, setCurrentUser }: UserPageProps) => {
         };
 
         addComponentMutation.mutate({
+            version: userpage!.version,
             index: creatingPostAtIndex,
             component,
         });
@@ -150,14 +157,18 @@ export const Userpage = ({ currentUser, setCurrentUser }: UserPageProps) => {
 
     const handleUpdateComponent = (index: number, component: UserpageComponent) => {
         updateComponentMutation.mutate({
-            index,
+            version: userpage!.version,
+            component_id: userpage!.components[index].id,
             component,
         });
     };
 
     const handleDeleteComponent = (index: number) => {
         if (confirm('Are you sure you want to delete this component?')) {
-            deleteComponentMutation.mutate({ index });
+            deleteComponentMutation.mutate({
+                version: userpage!.version,
+                component_id: userpage!.components[index].id,
+            });
         }
     };
 
@@ -187,7 +198,10 @@ export const Userpage = ({ currentUser, setCurrentUser }: UserPageProps) => {
         }
 
         moveComponentMutation.mutate({
-            prev_index: draggedIndex,
+            version: userpage!.version,
+            component_id: userpage!.components[draggedIndex].id,
+            // Dropping past the last component appends it
+            before_component_id: userpage!.components[dropIndex]?.id,
             new_index: dropIndex,
         });
 
@@ -228,6 +242,14 @@ export const Userpage = ({ currentUser, setCurrentUser }: UserPageProps) => {
             );
         } else if (component.divider) {
             return <DividerComponentView component={component.divider} />;
+        } else if (component.quote) {
+            return <QuoteComponentView component={component.quote} />;
+        } else if (component.link_list) {
+            return <LinkListComponentView component={component.link_list} />;
+        } else if (component.image) {
+            return <ImageComponentView component={component.image} />;
+        } else if (component.collection) {
+            return <CollectionComponentView component={component.collection} />;
         }
         return null;
     };
@@ -4,6 +4,11 @@ export interface User {
     email?: string;
 }
 
+// An empty type means the kind of post is unknown
+export type PostType = 'article' | 'blog' | 'video' | 'podcast' | 'paper';
+
+export const POST_TYPES: PostType[] = ['article', 'blog', 'video', 'podcast', 'paper'];
+
 export interface Post {
     id?: string;
     title: string;
@@ -11,15 +16,37 @@ export interface Post {
     thumbnail_url: string;
     site_name: string;
     url: string;
-    type: string;
+    canonical_url?: string;
+    type: PostType | '';
     author: string;
+    reactions?: ReactionSummary;
+    my_reaction?: Reaction;
+}
+
+export interface PostPage {
+    posts: Post[];
+    next_cursor?: string;
+}
+
+export type FeedSort = 'top' | 'newest' | 'hot' | 'important' | 'controversial';
+
+export interface ReactionSummary {
+    total: number;
+    counts: Partial<Record<ReactionType, number>>;
+    scores: Record<ReactionAxis, number>;
 }
 
+export type ReactionValue = -2 | -1 | 0 | 1 | 2;
+
+export type ReactionAxis = 'agreement' | 'importance' | 'vote';
+
 export interface Reaction {
     id: string;
-    reaction_type: string;
     user_id: string;
     post_id: string;
+    agreement: ReactionValue;
+    importance: ReactionValue;
+    vote: ReactionValue;
 }
 
 export type ReactionType =
@@ -40,6 +67,8 @@ export type ReactionType =
 export interface PostComponentData {
     post_id: string;
     size: 1 | 2 | 3; // 1=large (not implemented), 2=medium (thumbnail+title+desc), 3=small (title only)
+    post?: Post; // resolved by the server
+    missing?: boolean; // set instead of post if the post was deleted
 }
 
 export interface HeaderComponentData {
@@ -48,25 +77,91 @@ export interface HeaderComponentData {
 }
 
 export interface ParagraphComponentData {
-    content: string;
+    content: string; // Markdown source
+    content_html?: string; // sanitized HTML rendered by the server
 }
 
 export interface DividerComponentData {
     style: 'regular';
 }
 
+export interface QuoteComponentData {
+    content: string;
+    attribution: string;
+}
+
+export interface LinkData {
+    url: string;
+    title: string;
+}
+
+export interface LinkListComponentData {
+    title: string;
+    links: LinkData[];
+}
+
+export interface ImageComponentData {
+    url: string;
+    caption: string;
+    alt: string;
+}
+
+// Embeds a section of another user's page, starting at the header section_id
+export interface CollectionComponentData {
+    user_id: string;
+    section_id: string;
+    user?: User; // resolved by the server
+    components?: UserpageComponent[]; // resolved by the server, one level deep
+    missing?: boolean; // set instead if the section no longer exists
+}
+
 // Backend wraps components in this structure
 export interface UserpageComponent {
+    id?: string; // assigned by the server
     header?: HeaderComponentData;
     post?: PostComponentData;
     paragraph?: ParagraphComponentData;
     divider?: DividerComponentData;
+    quote?: QuoteComponentData;
+    link_list?: LinkListComponentData;
+    image?: ImageComponentData;
+    collection?: CollectionComponentData;
 }
 
+// Unlisted pages are visible to anyone with their share link
+export type UserpageVisibility = 'public' | 'unlisted' | 'private';
+
 export interface Userpage {
     id: string;
     user_id: string;
+    slug: string; // "main" for the default page
+    title: string;
+    visibility: UserpageVisibility;
+    share_token?: string; // only on unlisted pages
+    position: number;
     components: UserpageComponent[];
+    version: number;
+    updated_at?: string;
+}
+
+// A userpage without its components, as listed by GET /userpages
+export interface UserpageSummary {
+    id: string;
+    slug: string;
+    title: string;
+    visibility: UserpageVisibility;
+    position: number;
+    version: number;
+    updated_at: string;
+}
+
+// An earlier version of a userpage; components are only sent when previewing one
+export interface UserpageRevision {
+    id: string;
+    userpage_id: string;
+    version: number;
+    updated_at: string;
+    components?: UserpageComponent[];
 }
 
 // API Request/Response Types
@@ -88,20 +183,60 @@ export interface LoginRequest {
 }
 
 export interface AddComponentRequest {
-    index: number;
+    page?: string; // slug, defaults to the default page
+    version: number;
+    before_component_id?: string;
+    index?: number;
     component: UserpageComponent;
 }
 
 export interface MoveComponentRequest {
-    prev_index: number;
-    new_index: number;
+    page?: string; // slug, defaults to the default page
+    version: number;
+    component_id?: string;
+    before_component_id?: string;
+    prev_index?: number;
+    new_index?: number;
 }
 
 export interface UpdateComponentRequest {
-    index: number;
+    page?: string; // slug, defaults to the default page
+    version: number;
+    component_id?: string;
+    index?: number;
     component: UserpageComponent;
 }
 
 export interface DeleteComponentRequest {
-    index: number;
-}
\ No newline at end of file
+    page?: string; // slug, defaults to the default page
+    version: number;
+    component_id?: string;
+    index?: number;
+}
+
+// Batch operations take the fields of the matching single request, without page and version
+export type BatchOperation =
+    | ({ op: 'add' } & Omit<AddComponentRequest, 'page' | 'version'>)
+    | ({ op: 'update' } & Omit<UpdateComponentRequest, 'page' | 'version'>)
+    | ({ op: 'delete' } & Omit<DeleteComponentRequest, 'page' | 'version'>)
+    | ({ op: 'move' } & Omit<MoveComponentRequest, 'page' | 'version'>);
+
+export interface BatchRequest {
+    page?: string; // slug, defaults to the default page
+    version: number;
+    operations: BatchOperation[];
+}
+
+export interface CreateUserpageRequest {
+    slug: string;
+    title: string;
+    visibility: UserpageVisibility;
+}
+
+export interface UpdateUserpageRequest {
+    page: string; // current slug
+    version: number;
+    slug: string;
+    title: string;
+    visibility: UserpageVisibility;
+}